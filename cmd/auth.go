@@ -0,0 +1,277 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/quicdb/quic-cli/internal/api"
+	"github.com/quicdb/quic-cli/internal/auth"
+	"github.com/quicdb/quic-cli/internal/output"
+	"github.com/quicdb/quic-cli/internal/userconfig"
+	"github.com/spf13/cobra"
+)
+
+// Login flows that only exist for environment credentials
+const (
+	flowEnv    = "env"
+	flowEnvM2M = "env-m2m"
+)
+
+// flowDescriptions are the human-readable names of the login flows
+var flowDescriptions = map[string]string{
+	auth.FlowPKCE:   "browser login (PKCE)",
+	auth.FlowDevice: "device code login",
+	auth.FlowM2M:    "machine-to-machine (client credentials)",
+	flowEnv:         "environment (" + auth.EnvAccessToken + ")",
+	flowEnvM2M:      "environment (" + auth.EnvClientID + "/" + auth.EnvClientSecret + ")",
+}
+
+// authStatus is the session as rendered by 'quic auth status'
+type authStatus struct {
+	Profile        string     `json:"profile" yaml:"profile"`
+	Flow           string     `json:"flow" yaml:"flow"`
+	Subject        string     `json:"subject,omitempty" yaml:"subject,omitempty"`
+	Organization   string     `json:"organization,omitempty" yaml:"organization,omitempty"`
+	Scopes         []string   `json:"scopes,omitempty" yaml:"scopes,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+	Expired        bool       `json:"expired" yaml:"expired"`
+	RefreshToken   bool       `json:"refresh_token" yaml:"refresh_token"`
+	M2MCredentials bool       `json:"m2m_credentials" yaml:"m2m_credentials"`
+	Verified       *bool      `json:"verified,omitempty" yaml:"verified,omitempty"`
+}
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Inspect authentication",
+}
+
+var authStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show who you are logged in as",
+	Long: `Show who you are logged in as, how, and until when.
+
+The claims are decoded from the stored access token without contacting
+QuicDB. Use --verify to also check the session against the API, refreshing
+the token if needed.`,
+	Args: usageArgs(cobra.NoArgs),
+	RunE: runAuthStatus,
+}
+
+// tokenOutput is the access token as rendered by 'quic auth token'
+type tokenOutput struct {
+	AccessToken string     `json:"access_token" yaml:"access_token"`
+	TokenType   string     `json:"token_type" yaml:"token_type"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+	ExpiresIn   int        `json:"expires_in,omitempty" yaml:"expires_in,omitempty"` // seconds
+}
+
+var authTokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Print a valid access token",
+	Long: `Print a valid access token for calling the QuicDB API directly, refreshing
+the stored token first if it is about to expire.
+
+  curl -H "Authorization: Bearer $(quic auth token)" ...
+
+Use --json to also get the token's expiry.`,
+	Args: usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireLogin(); err != nil {
+			return err
+		}
+
+		client := api.NewClient()
+		token, err := client.AccessToken()
+		if err != nil {
+			return err
+		}
+
+		expiry := tokenExpiry(token)
+
+		// A stale token means the proactive refresh failed; retry it to
+		// surface the reason
+		if expiry != nil && time.Now().After(*expiry) {
+			if env := auth.LoadEnvCredentials(); env != nil {
+				return withExitCode(ExitAuthRequired, fmt.Errorf("the access token from %s has expired", envCredentialVars(env)))
+			}
+			if err := auth.RefreshAccessToken(token); err != nil {
+				return withExitCode(ExitAuthRequired, fmt.Errorf("access token expired and refresh failed: %w", err))
+			}
+			if token, err = client.AccessToken(); err != nil {
+				return err
+			}
+			expiry = tokenExpiry(token)
+		}
+
+		out := tokenOutput{AccessToken: token, TokenType: "Bearer", ExpiresAt: expiry}
+		if expiry != nil {
+			out.ExpiresIn = int(time.Until(*expiry).Seconds())
+		}
+
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+			outputFlag = string(output.JSON)
+		}
+		return printOutput(out, func(w io.Writer) error {
+			_, err := fmt.Fprintln(w, token)
+			return err
+		})
+	},
+}
+
+// tokenExpiry returns when token expires, preferring the stored expiry of the
+// active profile's token over the token's exp claim
+func tokenExpiry(token string) *time.Time {
+	if auth.LoadEnvCredentials() == nil {
+		if expiry, err := auth.LoadAccessTokenExpiry(); err == nil {
+			return &expiry
+		}
+	}
+	if claims, err := auth.ParseClaims(token); err == nil && claims.ExpiresAt != 0 {
+		expiry := time.Unix(claims.ExpiresAt, 0)
+		return &expiry
+	}
+	return nil
+}
+
+var whoamiCmd = &cobra.Command{
+	Use:   "whoami",
+	Short: "Show who you are logged in as",
+	Long:  authStatusCmd.Long,
+	Args:  usageArgs(cobra.NoArgs),
+	RunE:  runAuthStatus,
+}
+
+func init() {
+	authStatusCmd.Flags().Bool("verify", false, "Verify the session with an API call")
+	whoamiCmd.Flags().Bool("verify", false, "Verify the session with an API call")
+
+	authTokenCmd.Flags().Bool("json", false, "Print the token with its expiry as JSON (same as --output json)")
+
+	authCmd.AddCommand(authStatusCmd)
+	authCmd.AddCommand(authTokenCmd)
+}
+
+func runAuthStatus(cmd *cobra.Command, args []string) error {
+	if err := requireLogin(); err != nil {
+		return err
+	}
+
+	status := authStatus{Profile: userconfig.Profile()}
+	client := api.NewClient()
+
+	// Verify first so the claims shown are those of a refreshed token
+	var verifyErr error
+	if verify, _ := cmd.Flags().GetBool("verify"); verify {
+		_, verifyErr = client.ListClusters(context.Background())
+		verified := verifyErr == nil
+		status.Verified = &verified
+	}
+
+	var token string
+	if env := auth.LoadEnvCredentials(); env != nil {
+		status.Flow = flowEnv
+		token = env.AccessToken
+		if env.IsM2M() {
+			status.Flow = flowEnvM2M
+			status.Subject = env.ClientID
+			status.M2MCredentials = true
+			// Only exchanged once the client has made a request
+			if status.Verified != nil && *status.Verified {
+				token, _ = client.AccessToken()
+			}
+		}
+	} else {
+		status.Flow = auth.LoadLoginFlow()
+		token, _ = auth.LoadToken(auth.AccessToken)
+		_, err := auth.LoadToken(auth.RefreshToken)
+		status.RefreshToken = err == nil
+		_, err = auth.LoadToken(auth.M2MClientID)
+		status.M2MCredentials = err == nil
+
+		if expiry, err := auth.LoadAccessTokenExpiry(); err == nil {
+			status.ExpiresAt = &expiry
+		}
+	}
+
+	if claims, err := auth.ParseClaims(token); err == nil {
+		if claims.Subject != "" {
+			status.Subject = claims.Subject
+		}
+		status.Organization = claims.Org()
+		status.Scopes = claims.Scopes()
+		if status.ExpiresAt == nil && claims.ExpiresAt != 0 {
+			expiry := time.Unix(claims.ExpiresAt, 0)
+			status.ExpiresAt = &expiry
+		}
+	}
+	status.Expired = status.ExpiresAt != nil && time.Now().After(*status.ExpiresAt)
+
+	if err := printOutput(status, func(w io.Writer) error {
+		return printAuthStatus(w, status)
+	}); err != nil {
+		return err
+	}
+
+	if verifyErr != nil {
+		return fmt.Errorf("session verification failed: %w", verifyErr)
+	}
+	return nil
+}
+
+func printAuthStatus(w io.Writer, status authStatus) error {
+	who := status.Subject
+	if who == "" {
+		who = "unknown subject"
+	}
+	fmt.Fprintf(w, "Logged in to profile '%s' as %s\n", status.Profile, who)
+
+	flow := flowDescriptions[status.Flow]
+	if flow == "" {
+		flow = status.Flow
+	}
+	fmt.Fprintf(w, "  Flow:            %s\n", flow)
+
+	if status.Organization != "" {
+		fmt.Fprintf(w, "  Organization:    %s\n", status.Organization)
+	}
+	if len(status.Scopes) > 0 {
+		fmt.Fprintf(w, "  Scopes:          %s\n", strings.Join(status.Scopes, " "))
+	}
+
+	switch {
+	case status.ExpiresAt == nil && status.Flow == flowEnvM2M:
+		fmt.Fprintln(w, "  Token expires:   exchanged on every invocation")
+	case status.ExpiresAt == nil:
+		fmt.Fprintln(w, "  Token expires:   unknown")
+	case status.Expired:
+		fmt.Fprintf(w, "  Token expires:   %s (expired %s ago)\n", status.ExpiresAt.Local().Format(time.DateTime), roundDuration(time.Since(*status.ExpiresAt)))
+	default:
+		fmt.Fprintf(w, "  Token expires:   %s (in %s)\n", status.ExpiresAt.Local().Format(time.DateTime), roundDuration(time.Until(*status.ExpiresAt)))
+	}
+
+	fmt.Fprintf(w, "  Refresh token:   %s\n", yesNo(status.RefreshToken))
+	fmt.Fprintf(w, "  M2M credentials: %s\n", yesNo(status.M2MCredentials))
+
+	if status.Verified != nil {
+		fmt.Fprintf(w, "  Verified:        %s\n", yesNo(*status.Verified))
+	}
+	return nil
+}
+
+// roundDuration trims a duration to a readable precision
+func roundDuration(d time.Duration) time.Duration {
+	if d >= time.Hour {
+		return d.Round(time.Minute)
+	}
+	return d.Round(time.Second)
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/quicdb/quic-cli/internal/api"
+	"github.com/quicdb/quic-cli/internal/cluster"
+	"github.com/quicdb/quic-cli/internal/connstring"
+	"github.com/quicdb/quic-cli/internal/pgprobe"
+	"github.com/quicdb/quic-cli/internal/progress"
+	"github.com/spf13/cobra"
+)
+
+var branchCmd = &cobra.Command{
+	Use:   "branch",
+	Short: "Inspect database branches",
+}
+
+var branchShowCmd = &cobra.Command{
+	Use:   "show <branch-name>",
+	Short: "Show details and connection info of an existing branch",
+	Args:  usageArgs(cobra.ExactArgs(1)),
+	RunE:  runBranchShow,
+}
+
+// urlCmd is a shortcut for 'quic branch show' that prints only the connection string
+var urlCmd = &cobra.Command{
+	Use:   "url <branch-name>",
+	Short: "Print the connection string of an existing branch",
+	Args:  usageArgs(cobra.ExactArgs(1)),
+	RunE:  runBranchShow,
+}
+
+var branchWaitCmd = &cobra.Command{
+	Use:   "wait <branch-name>",
+	Short: "Wait until a branch accepts Postgres connections",
+	Args:  usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		branchName := args[0]
+
+		// Check if user is authenticated
+		if err := requireLogin(); err != nil {
+			return err
+		}
+
+		flagCluster, _ := cmd.Flags().GetString("cluster")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		if timeout <= 0 {
+			return newUsageError(fmt.Errorf("--timeout must be positive"))
+		}
+
+		// Resolve which cluster to use
+		client := api.NewClient()
+		ctx := context.Background()
+		clusterID, err := cluster.ResolveCluster(ctx, flagCluster, client)
+		if err != nil {
+			return err
+		}
+
+		branch, err := client.GetBranch(ctx, clusterID, branchName)
+		if err != nil {
+			return fmt.Errorf("failed to get branch '%s': %w", branchName, err)
+		}
+
+		if err := waitForBranch(ctx, &branch.CreateBranchResponse, timeout); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(os.Stderr, "Branch '%s' is ready\n", branchName)
+		return nil
+	},
+}
+
+// waitForBranch probes the branch until it completes a Postgres handshake
+func waitForBranch(ctx context.Context, branch *api.CreateBranchResponse, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	spinner := progress.NewSpinner("Waiting for branch to accept connections...")
+	defer spinner.Stop()
+
+	err := pgprobe.WaitReady(ctx, pgprobe.Config{
+		Host:     branch.Host,
+		Port:     branch.Port,
+		User:     branch.User,
+		Password: branch.Password,
+		Database: branch.Database,
+	}, time.Second)
+	if err != nil && ctx.Err() != nil {
+		return withExitCode(ExitConflict, fmt.Errorf("timed out after %s: %w", timeout, err))
+	}
+	// Anything else, such as rejected credentials, will not resolve by waiting
+	return err
+}
+
+func runBranchShow(cmd *cobra.Command, args []string) error {
+	branchName := args[0]
+
+	// Check if user is authenticated
+	if err := requireLogin(); err != nil {
+		return err
+	}
+
+	flagCluster, _ := cmd.Flags().GetString("cluster")
+	formatFlag, _ := cmd.Flags().GetString("format")
+	format, err := connstring.ParseFormat(formatFlag)
+	if err != nil {
+		return newUsageError(err)
+	}
+
+	// Resolve which cluster to use
+	client := api.NewClient()
+	ctx := context.Background()
+	clusterID, err := cluster.ResolveCluster(ctx, flagCluster, client)
+	if err != nil {
+		return err
+	}
+
+	branch, err := client.GetBranch(ctx, clusterID, branchName)
+	if err != nil {
+		return fmt.Errorf("failed to get branch '%s': %w", branchName, err)
+	}
+
+	connectionString := connstring.Build(&branch.CreateBranchResponse, format)
+
+	return printOutput(branch, func(w io.Writer) error {
+		if cmd.Name() == "url" {
+			_, err := fmt.Fprintln(w, connectionString)
+			return err
+		}
+
+		fmt.Fprintf(w, "Branch:      %s\n", branch.Name)
+		fmt.Fprintf(w, "Cluster:     %s\n", branch.Cluster)
+		fmt.Fprintf(w, "Created by:  %s\n", branch.CreatedBy)
+		fmt.Fprintf(w, "Created at:  %s\n", branch.CreatedAt)
+		fmt.Fprintln(w)
+		_, err := fmt.Fprintln(w, connectionString)
+		return err
+	})
+}
+
+func init() {
+	for _, c := range []*cobra.Command{branchShowCmd, urlCmd} {
+		c.Flags().StringP("cluster", "c", "", "Cluster of the branch (ID, name, subdomain or unique ID prefix)")
+		c.Flags().StringP("format", "f", string(connstring.URI), "Connection string format: uri, env, dotenv, jdbc, keyvalue, psql or prisma")
+	}
+
+	branchWaitCmd.Flags().StringP("cluster", "c", "", "Cluster of the branch (ID, name, subdomain or unique ID prefix)")
+	branchWaitCmd.Flags().Duration("timeout", 2*time.Minute, "How long to wait for the branch")
+
+	branchCmd.AddCommand(branchShowCmd)
+	branchCmd.AddCommand(branchWaitCmd)
+}
@@ -2,34 +2,68 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"time"
 
 	"github.com/quicdb/quic-cli/internal/api"
-	"github.com/quicdb/quic-cli/internal/auth"
 	"github.com/quicdb/quic-cli/internal/cluster"
+	"github.com/quicdb/quic-cli/internal/connstring"
+	"github.com/quicdb/quic-cli/internal/dotenv"
+	"github.com/quicdb/quic-cli/internal/progress"
 	"github.com/spf13/cobra"
 )
 
 var checkoutCmd = &cobra.Command{
 	Use:   "checkout <branch-name>",
 	Short: "Create a new database branch",
-	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	Args:  usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
 		branchName := args[0]
 
 		// Check if user is authenticated
-		_, err := auth.LoadToken(auth.AccessToken)
-		if err != nil {
-			fmt.Println("You are not logged in. Please run 'quic login' first.")
-			return
+		if err := requireLogin(); err != nil {
+			return err
 		}
 
 		// Get cluster ID from flag
 		flagClusterID, err := cmd.Flags().GetString("cluster")
 		if err != nil {
-			fmt.Printf("Error getting cluster flag: %v\n", err)
-			return
+			return newUsageError(fmt.Errorf("error getting cluster flag: %w", err))
+		}
+
+		formatFlag, _ := cmd.Flags().GetString("format")
+		format, err := connstring.ParseFormat(formatFlag)
+		if err != nil {
+			return newUsageError(err)
+		}
+
+		var wait time.Duration
+		if waitFlag, _ := cmd.Flags().GetString("wait"); waitFlag != "" {
+			wait, err = time.ParseDuration(waitFlag)
+			if err != nil || wait <= 0 {
+				return newUsageError(fmt.Errorf("invalid --wait timeout %q", waitFlag))
+			}
+		}
+
+		var waitReady time.Duration
+		if waitReadyFlag, _ := cmd.Flags().GetString("wait-ready"); waitReadyFlag != "" {
+			waitReady, err = time.ParseDuration(waitReadyFlag)
+			if err != nil || waitReady <= 0 {
+				return newUsageError(fmt.Errorf("invalid --wait-ready timeout %q", waitReadyFlag))
+			}
+		}
+
+		reuse, _ := cmd.Flags().GetBool("reuse")
+		ifNotExists, _ := cmd.Flags().GetBool("if-not-exists")
+		reuse = reuse || ifNotExists
+
+		envFile, _ := cmd.Flags().GetString("env-file")
+		envVar, _ := cmd.Flags().GetString("env-var")
+		if envFile != "" && (format == connstring.Env || format == connstring.Dotenv || format == connstring.Psql) {
+			return newUsageError(fmt.Errorf("--env-file stores a single value and cannot be combined with --format %s", format))
 		}
 
 		// Resolve which cluster to use
@@ -37,42 +71,131 @@ var checkoutCmd = &cobra.Command{
 		ctx := context.Background()
 		clusterID, err := cluster.ResolveCluster(ctx, flagClusterID, client)
 		if err != nil {
-			fmt.Println(err)
-			return
+			return err
 		}
 
-		// Create the branch
-		branch, err := client.CreateBranch(ctx, clusterID, branchName, 90*time.Second)
+		// Create the branch, optionally waiting for the cluster to be ready
+		var branch *api.CreateBranchResponse
+		if wait > 0 {
+			branch, err = waitAndCreateBranch(ctx, client, clusterID, branchName, reuse, wait)
+		} else {
+			branch, err = createBranch(ctx, client, clusterID, branchName, reuse)
+		}
 		if err != nil {
-			var apiErr *api.APIError
-			if e, ok := err.(*api.APIError); ok {
-				apiErr = e
-			}
+			return err
+		}
 
-			if apiErr != nil && apiErr.StatusCode == 409 {
-				// Conflict - likely cluster not ready
-				fmt.Printf("Error: Cannot create branch '%s'\n", branchName)
-				fmt.Printf("%s\n\n", apiErr.Message)
-				fmt.Println("Please wait for the cluster to be ready before creating branches.")
-			} else {
-				fmt.Printf("Failed to create branch: %v\n", err)
+		// Make sure the branch accepts connections before handing it out
+		if waitReady > 0 {
+			if err := waitForBranch(ctx, branch, waitReady); err != nil {
+				return fmt.Errorf("branch created but failed to connect: %w", err)
 			}
-			return
 		}
 
-		// Output PostgreSQL connection string
-		connectionString := fmt.Sprintf("postgresql://%s:%s@%s:%d/%s",
-			branch.User,
-			branch.Password,
-			branch.Host,
-			branch.Port,
-			branch.Database,
-		)
+		// Output PostgreSQL connection details in the requested format
+		connectionString := connstring.Build(branch, format)
+
+		// Merge the connection string into the project's dotenv file
+		if envFile != "" {
+			if err := dotenv.Set(envFile, envVar, connectionString); err != nil {
+				return fmt.Errorf("branch created but failed to update %s: %w", envFile, err)
+			}
+		}
 
-		fmt.Println(connectionString)
+		return printOutput(branch, func(w io.Writer) error {
+			if envFile != "" {
+				// Keep the password out of terminal scrollback and CI logs
+				_, err := fmt.Fprintf(w, "Wrote %s to %s\n", envVar, envFile)
+				return err
+			}
+			_, err := fmt.Fprintln(w, connectionString)
+			return err
+		})
 	},
 }
 
 func init() {
-	checkoutCmd.Flags().StringP("cluster", "c", "", "Cluster ID to create the branch from")
+	checkoutCmd.Flags().StringP("cluster", "c", "", "Cluster to create the branch from (ID, name, subdomain or unique ID prefix)")
+	checkoutCmd.Flags().StringP("format", "f", string(connstring.URI), "Connection string format: uri, env, dotenv, jdbc, keyvalue, psql or prisma")
+	checkoutCmd.Flags().String("wait", "", "Wait up to this long for the cluster to accept new branches (default 10m when given without a value)")
+	checkoutCmd.Flags().Lookup("wait").NoOptDefVal = "10m"
+	checkoutCmd.Flags().String("wait-ready", "", "Wait up to this long for the branch to accept Postgres connections (default 2m when given without a value)")
+	checkoutCmd.Flags().Lookup("wait-ready").NoOptDefVal = "2m"
+	checkoutCmd.Flags().Bool("reuse", false, "Return the existing branch instead of failing if it already exists")
+	checkoutCmd.Flags().Bool("if-not-exists", false, "Alias for --reuse")
+	checkoutCmd.Flags().String("env-file", "", "Write the connection string into this dotenv file")
+	checkoutCmd.Flags().String("env-var", "DATABASE_URL", "Variable name to use with --env-file")
+}
+
+// createBranch creates the branch, or with reuse returns the connection
+// details of an existing branch of the same name. Without reuse an existing
+// branch fails with ExitConflict.
+func createBranch(ctx context.Context, client *api.Client, clusterID, branchName string, reuse bool) (*api.CreateBranchResponse, error) {
+	branch, err := client.CreateBranch(ctx, clusterID, branchName, 90*time.Second)
+	if err == nil {
+		return branch, nil
+	}
+
+	var apiErr *api.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 409 {
+		return nil, fmt.Errorf("failed to create branch: %w", err)
+	}
+
+	// A conflict means either the branch exists or the cluster is not ready;
+	// looking the branch up tells the two apart
+	existing, lookupErr := client.GetBranch(ctx, clusterID, branchName)
+	if lookupErr == nil {
+		if reuse {
+			fmt.Fprintf(os.Stderr, "Branch '%s' already exists, reusing it\n", branchName)
+			return &existing.CreateBranchResponse, nil
+		}
+		// Not wrapping the API error keeps waitAndCreateBranch from retrying
+		return nil, withExitCode(ExitConflict, fmt.Errorf("branch '%s' already exists (use --reuse to return it instead)", branchName))
+	}
+	var lookupAPIErr *api.APIError
+	if !errors.As(lookupErr, &lookupAPIErr) || lookupAPIErr.StatusCode != 404 {
+		return nil, fmt.Errorf("failed to look up existing branch '%s': %w", branchName, lookupErr)
+	}
+
+	// Conflict - likely cluster not ready
+	return nil, fmt.Errorf("cannot create branch '%s': %w\n\n"+
+		"Please wait for the cluster to be ready before creating branches.", branchName, apiErr)
+}
+
+// waitAndCreateBranch polls the cluster status with backoff until the cluster
+// accepts the new branch or the timeout expires
+func waitAndCreateBranch(ctx context.Context, client *api.Client, clusterID, branchName string, reuse bool, timeout time.Duration) (*api.CreateBranchResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	spinner := progress.NewSpinner("Waiting for cluster to become ready...")
+	defer spinner.Stop()
+
+	delay := 2 * time.Second
+	for {
+		status, err := client.GetClusterStatus(ctx, clusterID)
+		if err != nil && ctx.Err() == nil {
+			return nil, fmt.Errorf("failed to get cluster status: %w", err)
+		}
+
+		if status != nil && status.Ready {
+			spinner.Update("Creating branch...")
+			branch, err := createBranch(ctx, client, clusterID, branchName, reuse)
+			var apiErr *api.APIError
+			if err == nil || !errors.As(err, &apiErr) || apiErr.StatusCode != 409 {
+				return branch, err
+			}
+			// Still conflicting, the cluster is not quite ready yet
+			spinner.Update("Cluster is finishing startup...")
+		} else if status != nil {
+			spinner.Update(fmt.Sprintf("Waiting for cluster to become ready (status: %s)...", status.Status))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, withExitCode(ExitConflict, fmt.Errorf("timed out after %s waiting for the cluster to become ready", timeout))
+		case <-time.After(delay):
+		}
+		delay = min(delay*3/2, 15*time.Second)
+	}
 }
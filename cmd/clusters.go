@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/quicdb/quic-cli/internal/api"
+	"github.com/quicdb/quic-cli/internal/cluster"
+	"github.com/quicdb/quic-cli/internal/userconfig"
+	"github.com/spf13/cobra"
+)
+
+// clusterListItem is a cluster as rendered by 'quic clusters ls'
+type clusterListItem struct {
+	api.Cluster `yaml:",inline"`
+	Default     bool `json:"default" yaml:"default"`
+}
+
+var clustersCmd = &cobra.Command{
+	Use:   "clusters",
+	Short: "Manage clusters",
+}
+
+var clustersLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List available clusters",
+	Args:  usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Check if user is authenticated
+		if err := requireLogin(); err != nil {
+			return err
+		}
+
+		client := api.NewClient()
+		ctx := context.Background()
+
+		clusters, err := client.ListClusters(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list clusters: %w", err)
+		}
+
+		selectedCluster, err := userconfig.GetSelectedCluster()
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+
+		items := make([]clusterListItem, len(clusters))
+		for i, c := range clusters {
+			items[i] = clusterListItem{Cluster: c, Default: c.ID == selectedCluster}
+		}
+
+		return printOutput(items, func(w io.Writer) error {
+			if len(clusters) == 0 {
+				fmt.Fprintln(w, "No clusters found. Please create a cluster in the dashboard first")
+				return nil
+			}
+			_, err := fmt.Fprint(w, cluster.FormatClusterTable(clusters, selectedCluster))
+			return err
+		})
+	},
+}
+
+var clustersUseCmd = &cobra.Command{
+	Use:   "use <cluster>",
+	Long:  "Set the default cluster. The cluster may be given by ID, name, subdomain or a unique ID prefix of at least 8 characters.",
+	Short: "Set the default cluster",
+	Args:  usageArgs(cobra.ExactArgs(1)),
+	RunE:  runUseCluster,
+}
+
+// runUseCluster validates the cluster given as the only argument and makes it
+// the default. It backs both 'quic clusters use' and 'quic config cluster'.
+func runUseCluster(cmd *cobra.Command, args []string) error {
+	// Check if user is authenticated
+	if err := requireLogin(); err != nil {
+		return err
+	}
+
+	// Validate the cluster before persisting it
+	client := api.NewClient()
+	clusters, err := client.ListClusters(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to list clusters: %w", err)
+	}
+
+	selected, err := cluster.Match(clusters, args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := userconfig.SetSelectedCluster(selected.ID); err != nil {
+		return fmt.Errorf("failed to set selected cluster: %w", err)
+	}
+
+	return printOutput(selected, func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, "Selected cluster set to: %s (%s)\n", selected.Name, selected.ID)
+		return err
+	})
+}
+
+func init() {
+	clustersCmd.AddCommand(clustersLsCmd)
+	clustersCmd.AddCommand(clustersUseCmd)
+}
@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 
+	"github.com/quicdb/quic-cli/internal/auth"
 	"github.com/quicdb/quic-cli/internal/userconfig"
 	"github.com/spf13/cobra"
 )
@@ -13,40 +15,90 @@ var configCmd = &cobra.Command{
 }
 
 var configClusterCmd = &cobra.Command{
-	Use:   "cluster <cluster-id>",
+	Use:   "cluster <cluster>",
 	Short: "Set the selected cluster",
-	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		clusterID := args[0]
+	Long:  "Set the selected cluster. The cluster may be given by ID, name, subdomain or a unique ID prefix of at least 8 characters.",
+	Args:  usageArgs(cobra.ExactArgs(1)),
+	RunE:  runUseCluster,
+}
+
+var configCredentialStoreCmd = &cobra.Command{
+	Use:   "credential-store <auto|keyring|file>",
+	Short: "Set where credentials are stored",
+	Long: `Set where credentials are stored.
+
+  keyring  the OS keychain/credential manager
+  file     an encrypted file in ~/.config/quic, keyed by QUIC_CREDENTIALS_PASSPHRASE
+           or, when unset, by a key derived from this machine and user
+  auto     the keyring when available, otherwise the encrypted file (default)
+
+QUIC_CREDENTIAL_STORE overrides this setting. Existing credentials are not
+migrated, so run 'quic login' again after switching.`,
+	Args:      usageArgs(cobra.ExactArgs(1)),
+	ValidArgs: []string{auth.StoreAuto, auth.StoreKeyring, auth.StoreFile},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		backend := args[0]
+		switch backend {
+		case auth.StoreAuto, auth.StoreKeyring, auth.StoreFile:
+		default:
+			return newUsageError(fmt.Errorf("invalid credential store %q (expected auto, keyring or file)", backend))
+		}
 
-		if err := userconfig.SetSelectedCluster(clusterID); err != nil {
-			fmt.Printf("Failed to set selected cluster: %v\n", err)
-			return
+		if err := userconfig.SetCredentialStore(backend); err != nil {
+			return fmt.Errorf("failed to set credential store: %w", err)
 		}
 
-		fmt.Printf("Selected cluster set to: %s\n", clusterID)
+		return printOutput(configView{Profile: userconfig.Profile(), CredentialStore: backend}, func(w io.Writer) error {
+			_, err := fmt.Fprintf(w, "Credential store set to: %s\n", backend)
+			return err
+		})
 	},
 }
 
+// configView is the configuration as rendered by 'quic config show'
+type configView struct {
+	Profile         string `json:"profile" yaml:"profile"`
+	SelectedCluster string `json:"selected_cluster,omitempty" yaml:"selected_cluster,omitempty"`
+	CredentialStore string `json:"credential_store,omitempty" yaml:"credential_store,omitempty"`
+}
+
 var configShowCmd = &cobra.Command{
 	Use:   "show",
 	Short: "Show current configuration",
-	Run: func(cmd *cobra.Command, args []string) {
-		config, err := userconfig.Load()
+	Args:  usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		settings, err := userconfig.LoadSettings()
 		if err != nil {
-			fmt.Printf("Failed to load configuration: %v\n", err)
-			return
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+		profile, err := userconfig.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
 		}
 
-		if config.SelectedCluster == "" {
-			fmt.Println("No cluster selected")
-		} else {
-			fmt.Printf("Selected cluster: %s\n", config.SelectedCluster)
+		config := configView{
+			Profile:         userconfig.Profile(),
+			SelectedCluster: profile.SelectedCluster,
+			CredentialStore: settings.CredentialStore,
 		}
+
+		return printOutput(config, func(w io.Writer) error {
+			fmt.Fprintf(w, "Profile: %s\n", config.Profile)
+			if config.SelectedCluster == "" {
+				fmt.Fprintln(w, "No cluster selected")
+			} else {
+				fmt.Fprintf(w, "Selected cluster: %s\n", config.SelectedCluster)
+			}
+			if config.CredentialStore != "" {
+				fmt.Fprintf(w, "Credential store: %s\n", config.CredentialStore)
+			}
+			return nil
+		})
 	},
 }
 
 func init() {
 	configCmd.AddCommand(configClusterCmd)
+	configCmd.AddCommand(configCredentialStoreCmd)
 	configCmd.AddCommand(configShowCmd)
 }
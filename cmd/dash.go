@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 	"os/exec"
 	"runtime"
 
@@ -11,8 +12,8 @@ import (
 var dashCmd = &cobra.Command{
 	Use:   "dash",
 	Short: "Open QuicDB dashboard in browser",
-	Args:  cobra.NoArgs,
-	Run: func(cmd *cobra.Command, args []string) {
+	Args:  usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
 		url := "https://dash.quicdb.com"
 
 		var err error
@@ -28,11 +29,12 @@ var dashCmd = &cobra.Command{
 		}
 
 		if err != nil {
-			fmt.Printf("Failed to open browser: %v\n", err)
-			fmt.Printf("Please visit: %s\n", url)
-			return
+			fmt.Fprintf(os.Stderr, "Failed to open browser: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Please visit: %s\n", url)
+			return nil
 		}
 
-		fmt.Printf("Opening %s in your browser...\n", url)
+		fmt.Fprintf(os.Stderr, "Opening %s in your browser...\n", url)
+		return nil
 	},
 }
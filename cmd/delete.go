@@ -3,32 +3,39 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"io"
 
 	"github.com/quicdb/quic-cli/internal/api"
-	"github.com/quicdb/quic-cli/internal/auth"
 	"github.com/quicdb/quic-cli/internal/cluster"
+	"github.com/quicdb/quic-cli/internal/dotenv"
 	"github.com/spf13/cobra"
 )
 
+// deleteResult is a deleted branch as rendered by 'quic delete'
+type deleteResult struct {
+	Branch    string `json:"branch" yaml:"branch"`
+	ClusterID string `json:"cluster_id" yaml:"cluster_id"`
+	// EnvFile and EnvVar are set when --env-file had the variable removed
+	EnvFile string `json:"env_file,omitempty" yaml:"env_file,omitempty"`
+	EnvVar  string `json:"env_var,omitempty" yaml:"env_var,omitempty"`
+}
+
 var deleteCmd = &cobra.Command{
 	Use:   "delete <branch-name>",
 	Short: "Delete a database branch",
-	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	Args:  usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
 		branchName := args[0]
 
 		// Check if user is authenticated
-		_, err := auth.LoadToken(auth.AccessToken)
-		if err != nil {
-			fmt.Println("You are not logged in. Please run 'quic login' first.")
-			return
+		if err := requireLogin(); err != nil {
+			return err
 		}
 
 		// Get cluster ID from flag
 		flagClusterID, err := cmd.Flags().GetString("cluster")
 		if err != nil {
-			fmt.Printf("Error getting cluster flag: %v\n", err)
-			return
+			return newUsageError(fmt.Errorf("error getting cluster flag: %w", err))
 		}
 
 		// Resolve which cluster to use
@@ -36,21 +43,43 @@ var deleteCmd = &cobra.Command{
 		ctx := context.Background()
 		clusterID, err := cluster.ResolveCluster(ctx, flagClusterID, client)
 		if err != nil {
-			fmt.Println(err)
-			return
+			return err
 		}
 
 		// Delete the branch
 		err = client.DeleteBranch(ctx, clusterID, branchName)
 		if err != nil {
-			fmt.Printf("Failed to delete branch: %v\n", err)
-			return
+			return fmt.Errorf("failed to delete branch: %w", err)
+		}
+
+		result := deleteResult{Branch: branchName, ClusterID: clusterID}
+
+		// Remove the variable written by 'quic checkout --env-file'
+		envFile, _ := cmd.Flags().GetString("env-file")
+		if envFile != "" {
+			envVar, _ := cmd.Flags().GetString("env-var")
+			removed, err := dotenv.Unset(envFile, envVar)
+			if err != nil {
+				return fmt.Errorf("branch deleted but failed to update %s: %w", envFile, err)
+			}
+			if removed {
+				result.EnvFile = envFile
+				result.EnvVar = envVar
+			}
 		}
 
-		fmt.Printf("Branch '%s' scheduled for deletion\n", branchName)
+		return printOutput(result, func(w io.Writer) error {
+			fmt.Fprintf(w, "Branch '%s' scheduled for deletion\n", result.Branch)
+			if result.EnvVar != "" {
+				fmt.Fprintf(w, "Removed %s from %s\n", result.EnvVar, result.EnvFile)
+			}
+			return nil
+		})
 	},
 }
 
 func init() {
-	deleteCmd.Flags().StringP("cluster", "c", "", "Cluster ID to delete the branch from")
+	deleteCmd.Flags().StringP("cluster", "c", "", "Cluster to delete the branch from (ID, name, subdomain or unique ID prefix)")
+	deleteCmd.Flags().String("env-file", "", "Remove the connection string from this dotenv file")
+	deleteCmd.Flags().String("env-var", "DATABASE_URL", "Variable name to remove with --env-file")
 }
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/quicdb/quic-cli/internal/api"
+	"github.com/quicdb/quic-cli/internal/auth"
+	"github.com/quicdb/quic-cli/internal/cluster"
+	"github.com/quicdb/quic-cli/internal/userconfig"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes returned by quic. Scripts may branch on these, so existing
+// values must never be renumbered.
+const (
+	ExitOK           = 0 // Command succeeded
+	ExitError        = 1 // Unclassified failure
+	ExitUsage        = 2 // Invalid arguments or flags
+	ExitAuthRequired = 3 // Not logged in, or the session could not be refreshed
+	ExitNotFound     = 4 // Branch, cluster or other resource does not exist
+	ExitConflict     = 5 // Resource already exists or the cluster is not ready
+	ExitNetwork      = 6 // API unreachable, timed out, rate limited or unavailable
+)
+
+// usageError marks errors caused by invalid command-line input
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string {
+	return e.err.Error()
+}
+
+func (e *usageError) Unwrap() error {
+	return e.err
+}
+
+// exitError forces a specific exit code for the wrapped error
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// newUsageError wraps err so that it exits with ExitUsage
+func newUsageError(err error) error {
+	return &usageError{err: err}
+}
+
+// withExitCode wraps err so that it exits with the given code
+func withExitCode(code int, err error) error {
+	return &exitError{code: code, err: err}
+}
+
+// usageArgs wraps a positional argument validator so its failures are
+// reported as usage errors
+func usageArgs(validate cobra.PositionalArgs) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := validate(cmd, args); err != nil {
+			return newUsageError(err)
+		}
+		return nil
+	}
+}
+
+// exitCode maps an error returned by a command to one of the documented exit codes
+func exitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+
+	var usageErr *usageError
+	if errors.As(err, &usageErr) {
+		return ExitUsage
+	}
+
+	if errors.Is(err, auth.ErrNotLoggedIn) {
+		return ExitAuthRequired
+	}
+
+	var notFoundErr *cluster.NotFoundError
+	if errors.As(err, &notFoundErr) {
+		return ExitNotFound
+	}
+
+	var ambiguousErr *cluster.AmbiguousError
+	if errors.As(err, &ambiguousErr) {
+		return ExitUsage
+	}
+
+	var apiErr *api.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case 401, 403:
+			return ExitAuthRequired
+		case 404:
+			return ExitNotFound
+		case 409:
+			return ExitConflict
+		case 429, 502, 503, 504:
+			// Still failing after retries, so scripts can treat it like an
+			// outage and try again later
+			return ExitNetwork
+		}
+		return ExitError
+	}
+
+	var urlErr *url.Error
+	var netErr net.Error
+	if errors.As(err, &urlErr) || errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) {
+		return ExitNetwork
+	}
+
+	// Cobra reports unknown subcommands with a plain error before any of our
+	// code runs, so this is the only way to recognise them
+	if strings.HasPrefix(err.Error(), "unknown command") {
+		return ExitUsage
+	}
+
+	return ExitError
+}
+
+// requireLogin returns auth.ErrNotLoggedIn when neither the environment nor
+// the credential store provides credentials. Failures to read the store are
+// returned as they are, since logging in again would not fix them.
+func requireLogin() error {
+	if auth.LoadEnvCredentials() != nil {
+		return nil
+	}
+	if _, err := auth.LoadToken(auth.AccessToken); err != nil {
+		if !errors.Is(err, auth.ErrNotFound) {
+			return fmt.Errorf("failed to read credentials: %w", err)
+		}
+		if profile := userconfig.Profile(); profile != userconfig.DefaultProfile {
+			return fmt.Errorf("profile '%s': %w", profile, auth.ErrNotLoggedIn)
+		}
+		return auth.ErrNotLoggedIn
+	}
+	return nil
+}
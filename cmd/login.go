@@ -8,18 +8,22 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
-	"net"
 	"net/http"
 	"net/url"
+	"os"
 	"os/exec"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/quicdb/quic-cli/internal/api"
 	"github.com/quicdb/quic-cli/internal/auth"
 	"github.com/quicdb/quic-cli/internal/config"
+	"github.com/quicdb/quic-cli/internal/prompt"
+	"github.com/quicdb/quic-cli/internal/retry"
+	"github.com/quicdb/quic-cli/internal/userconfig"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 // TokenResponse represents the response from the token exchange
@@ -37,129 +41,212 @@ type TokenResponse struct {
 var loginCmd = &cobra.Command{
 	Use:   "login",
 	Short: "Login with QuicDB",
-	Run: func(cmd *cobra.Command, args []string) {
+	Args:  usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
 		cfg := config.Get()
 
 		// Check for M2M authentication flags
 		clientID, _ := cmd.Flags().GetString("client-id")
-		clientSecret, _ := cmd.Flags().GetString("client-secret")
+		clientSecret, err := readClientSecret(cmd, clientID != "")
+		if err != nil {
+			return err
+		}
 
 		if clientID != "" && clientSecret != "" {
 			// M2M authentication flow
 			if err := loginM2M(cfg, clientID, clientSecret); err != nil {
-				fmt.Printf("M2M login failed: %v\n", err)
-				return
+				return fmt.Errorf("M2M login failed: %w", err)
 			}
-			fmt.Println("You're logged in!")
-			return
+			return finishLogin(auth.FlowM2M)
 		}
 
-		if clientID != "" || clientSecret != "" {
-			fmt.Println("Error: Both --client-id and --client-secret are required for M2M authentication")
-			return
+		if clientID != "" {
+			return newUsageError(fmt.Errorf("--client-id requires a client secret via --client-secret-stdin, --client-secret-file or %s", auth.EnvClientSecret))
+		}
+		if clientSecret != "" {
+			return newUsageError(fmt.Errorf("--client-id is required when passing a client secret"))
 		}
 
-		// Standard OAuth/PKCE flow
-		port := getOpenPort()
-		redirectURI := fmt.Sprintf("http://127.0.0.1:%d/callback", port)
+		// Device authorization flow for headless environments
+		if device, _ := cmd.Flags().GetBool("device"); device {
+			if err := loginDevice(cfg); err != nil {
+				return err
+			}
+			return finishLogin(auth.FlowDevice)
+		}
 
+		// Standard OAuth/PKCE flow
 		// Generate PKCE values
 		codeVerifier, err := generateCodeVerifier()
 		if err != nil {
-			fmt.Printf("Error generating code verifier: %v\n", err)
-			return
+			return fmt.Errorf("error generating code verifier: %w", err)
 		}
 		codeChallenge := generateCodeChallenge(codeVerifier)
 
-		// Start local server to receive the callback
-		server := &http.Server{
-			Addr: fmt.Sprintf(":%d", port),
+		// Random state ties the callback to this login attempt
+		state, err := generateCodeVerifier()
+		if err != nil {
+			return fmt.Errorf("error generating state: %w", err)
 		}
 
-		// Channel to receive the auth code
-		codeChan := make(chan string)
-		errorChan := make(chan error)
-
-		// Set up the callback handler
-		http.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
-			code := r.URL.Query().Get("code")
-			if code == "" {
-				errorChan <- fmt.Errorf("no code received in callback")
-				return
-			}
-
-			// Send success response to browser
-			w.Write([]byte("Authentication successful! You can close this window."))
-			codeChan <- code
-		})
-
-		// Start the server in a goroutine
-		go func() {
-			if err := server.ListenAndServe(); err != http.ErrServerClosed {
-				errorChan <- err
-			}
-		}()
+		// Start local server to receive the callback
+		server, err := startCallbackServer(state)
+		if err != nil {
+			return err
+		}
+		defer server.shutdown()
 
 		// Construct the auth URL with PKCE parameters
 		params := url.Values{}
 		params.Add("client_id", cfg.ClientID)
-		params.Add("redirect_uri", redirectURI)
+		params.Add("redirect_uri", server.redirectURI)
 		params.Add("response_type", "code")
 		params.Add("code_challenge", codeChallenge)
 		params.Add("code_challenge_method", "S256")
 		params.Add("scope", "offline_access")
+		params.Add("state", state)
 
 		authURL := fmt.Sprintf("%s?%s", cfg.AuthorizeURL, params.Encode())
 
-		fmt.Println("Opening browser for authentication...")
+		fmt.Fprintln(os.Stderr, "Opening browser for authentication...")
 
 		// Open the browser with the auth URL
 		err = openBrowser(authURL)
 		if err != nil {
-			fmt.Println("Please open the following URL in your browser:", authURL)
+			fmt.Fprintln(os.Stderr, "Please open the following URL in your browser:", authURL)
 		}
 
 		// Wait for either the code or an error
-		var code string
-		select {
-		case code = <-codeChan:
-			// Received authorization code
-		case err := <-errorChan:
-			fmt.Printf("Error: %v\n", err)
-			return
-		case <-time.After(5 * time.Minute):
-			fmt.Println("Timeout waiting for authentication")
-			return
+		code, err := server.wait(5 * time.Minute)
+		if err != nil {
+			return err
 		}
 
 		// Exchange the code for a token
 		token, err := exchangeCodeForToken(cfg.ClientID, cfg.ProjectID, code, codeVerifier, cfg.StytchURL)
 		if err != nil {
-			fmt.Printf("Error exchanging code for token: %v\n", err)
-			return
+			return withExitCode(ExitAuthRequired, fmt.Errorf("error exchanging code for token: %w", err))
+		}
+
+		if err := verifyTokenResponse(token); err != nil {
+			return withExitCode(ExitAuthRequired, err)
 		}
 
 		// Save tokens securely to OS keychain/credential manager
-		if err := auth.SaveToken(token.AccessToken, auth.AccessToken); err != nil {
-			fmt.Printf("Warning: Failed to save access token: %v\n", err)
+		if err := auth.SaveAccessToken(token.AccessToken, token.ExpiresIn); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to save access token: %v\n", err)
 		}
 
 		if token.RefreshToken != "" {
 			if err := auth.SaveToken(token.RefreshToken, auth.RefreshToken); err != nil {
-				fmt.Printf("Warning: Failed to save refresh token: %v\n", err)
+				fmt.Fprintf(os.Stderr, "Warning: Failed to save refresh token: %v\n", err)
 			}
 		}
 
-		fmt.Println("You're logged in!")
-
-		// Shutdown the server immediately
-		server.Close()
+		return finishLogin(auth.FlowPKCE)
 	},
 }
 
+// loginResult is a completed login as rendered by 'quic login'
+type loginResult struct {
+	Profile string `json:"profile" yaml:"profile"`
+	Flow    string `json:"flow" yaml:"flow"`
+}
+
+// finishLogin records the login flow and the profile that was logged in to,
+// creating it on first login, and reports success
+func finishLogin(flow string) error {
+	if err := auth.SaveToken(flow, auth.LoginFlow); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to save login flow: %v\n", err)
+	}
+	if err := userconfig.AddProfile(); err != nil {
+		return fmt.Errorf("logged in but failed to save profile: %w", err)
+	}
+
+	result := loginResult{Profile: userconfig.Profile(), Flow: flow}
+	return printOutput(result, func(w io.Writer) error {
+		if result.Profile != userconfig.DefaultProfile {
+			_, err := fmt.Fprintf(w, "You're logged in to profile '%s'!\n", result.Profile)
+			return err
+		}
+		_, err := fmt.Fprintln(w, "You're logged in!")
+		return err
+	})
+}
+
 func init() {
 	loginCmd.Flags().String("client-id", "", "M2M client ID for CI/CD authentication")
-	loginCmd.Flags().String("client-secret", "", "M2M client secret for CI/CD authentication")
+	loginCmd.Flags().String("client-secret", "", "M2M client secret for CI/CD authentication (insecure: visible in shell history and process listings)")
+	loginCmd.Flags().Bool("client-secret-stdin", false, "Read the M2M client secret from stdin")
+	loginCmd.Flags().String("client-secret-file", "", "Read the M2M client secret from a file")
+	loginCmd.Flags().Bool("device", false, "Login from another device by entering a code (for SSH sessions and containers)")
+}
+
+// verifyTokenResponse rejects tokens that were not issued to the CLI by the
+// project's identity provider before they are stored
+func verifyTokenResponse(token *TokenResponse) error {
+	if _, err := auth.VerifyToken(token.AccessToken); err != nil {
+		return fmt.Errorf("rejected access token: %w", err)
+	}
+	if token.IDToken != "" {
+		if _, err := auth.VerifyToken(token.IDToken); err != nil {
+			return fmt.Errorf("rejected ID token: %w", err)
+		}
+	}
+	return nil
+}
+
+// readClientSecret returns the M2M client secret from --client-secret-stdin,
+// --client-secret-file or --client-secret, falling back to QUIC_CLIENT_SECRET
+// when a client ID was given
+func readClientSecret(cmd *cobra.Command, withClientID bool) (string, error) {
+	var sources []string
+	for _, name := range []string{"client-secret", "client-secret-stdin", "client-secret-file"} {
+		if cmd.Flags().Changed(name) {
+			sources = append(sources, "--"+name)
+		}
+	}
+	if len(sources) > 1 {
+		return "", newUsageError(fmt.Errorf("%s cannot be combined", strings.Join(sources, " and ")))
+	}
+
+	if fromStdin, _ := cmd.Flags().GetBool("client-secret-stdin"); fromStdin {
+		if term.IsTerminal(int(os.Stdin.Fd())) {
+			return prompt.Secret("Client secret: ")
+		}
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("failed to read client secret from stdin: %w", err)
+		}
+		return requireSecret(strings.TrimSpace(string(data)), "stdin")
+	}
+
+	if path, _ := cmd.Flags().GetString("client-secret-file"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read client secret: %w", err)
+		}
+		return requireSecret(strings.TrimSpace(string(data)), path)
+	}
+
+	if secret, _ := cmd.Flags().GetString("client-secret"); secret != "" {
+		fmt.Fprintf(os.Stderr, "Warning: --client-secret exposes the secret in shell history and process listings. "+
+			"Use --client-secret-stdin, --client-secret-file or %s instead.\n", auth.EnvClientSecret)
+		return secret, nil
+	}
+
+	if withClientID {
+		return os.Getenv(auth.EnvClientSecret), nil
+	}
+	return "", nil
+}
+
+// requireSecret rejects an empty secret read from source
+func requireSecret(secret, source string) (string, error) {
+	if secret == "" {
+		return "", newUsageError(fmt.Errorf("no client secret found in %s", source))
+	}
+	return secret, nil
 }
 
 // generateCodeVerifier generates a random code verifier for PKCE
@@ -182,21 +269,6 @@ func generateCodeChallenge(verifier string) string {
 	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
 }
 
-// getOpenPort finds an available port
-func getOpenPort() int {
-	// Ask the OS for a free TCP port on all interfaces.
-	ln, err := net.Listen("tcp", ":0")
-	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
-	}
-	defer ln.Close()
-
-	// Extract the chosen port.
-	addr := ln.Addr().(*net.TCPAddr)
-	port := addr.Port
-	return port
-}
-
 // openBrowser opens a URL in the default browser
 func openBrowser(url string) error {
 	var cmd string
@@ -231,14 +303,14 @@ func loginM2M(cfg *config.Config, clientID, clientSecret string) error {
 	}
 
 	// Save access token
-	if err := auth.SaveToken(tokenResp.AccessToken, auth.AccessToken); err != nil {
+	if err := auth.SaveAccessToken(tokenResp.AccessToken, tokenResp.ExpiresIn); err != nil {
 		return fmt.Errorf("failed to save access token: %v", err)
 	}
 
 	// M2M tokens don't include refresh tokens, but check just in case
 	if tokenResp.RefreshToken != "" {
 		if err := auth.SaveToken(tokenResp.RefreshToken, auth.RefreshToken); err != nil {
-			fmt.Printf("Warning: Failed to save refresh token: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Warning: Failed to save refresh token: %v\n", err)
 		}
 	}
 
@@ -271,7 +343,7 @@ func exchangeCodeForToken(clientID, projectID, code, codeVerifier, stytchURL str
 	req.Header.Set("Content-Type", "application/json")
 
 	// Send the request
-	client := &http.Client{}
+	client := retry.NewClient(30 * time.Second)
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("error making request: %v", err)
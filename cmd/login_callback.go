@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"time"
+)
+
+// callbackResult is the outcome of the OAuth redirect to the loopback server
+type callbackResult struct {
+	code string
+	err  error
+}
+
+// callbackServer receives the OAuth authorization code on a loopback address
+type callbackServer struct {
+	server      *http.Server
+	listener    net.Listener
+	state       string
+	redirectURI string
+	result      chan callbackResult
+}
+
+// callbackPage is rendered in the browser after the redirect
+var callbackPage = template.Must(template.New("callback").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>QuicDB CLI</title>
+<style>
+  body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; display: flex; align-items: center; justify-content: center; height: 100vh; margin: 0; background: #f6f7f9; color: #1f2328; }
+  main { background: #fff; padding: 2rem 3rem; border-radius: 8px; box-shadow: 0 1px 3px rgba(0,0,0,.1); text-align: center; }
+  h1 { font-size: 1.25rem; }
+</style>
+</head>
+<body>
+<main>
+  <h1>{{.Title}}</h1>
+  <p>{{.Message}}</p>
+</main>
+</body>
+</html>
+`))
+
+// startCallbackServer listens on 127.0.0.1 only, so the authorization code is
+// never exposed on other interfaces. The state value is echoed back by the
+// authorization server and checked to reject forged redirects.
+func startCallbackServer(state string) (*callbackServer, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to start callback server: %w", err)
+	}
+
+	s := &callbackServer{
+		listener:    ln,
+		state:       state,
+		redirectURI: fmt.Sprintf("http://127.0.0.1:%d/callback", ln.Addr().(*net.TCPAddr).Port),
+		// Buffered so neither the handler nor Serve ever blocks on a reader
+		// that has already given up
+		result: make(chan callbackResult, 1),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", s.handleCallback)
+	s.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		if err := s.server.Serve(ln); err != nil && err != http.ErrServerClosed {
+			s.finish(callbackResult{err: fmt.Errorf("callback server failed: %w", err)})
+		}
+	}()
+
+	return s, nil
+}
+
+func (s *callbackServer) handleCallback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	if subtle.ConstantTimeCompare([]byte(query.Get("state")), []byte(s.state)) != 1 {
+		// Do not finish the flow: a stray or forged request must not abort
+		// a login the user is still completing
+		s.render(w, http.StatusBadRequest, "Authentication failed", "The login request could not be verified. Please run 'quic login' again.")
+		return
+	}
+
+	if errCode := query.Get("error"); errCode != "" {
+		msg := errCode
+		if desc := query.Get("error_description"); desc != "" {
+			msg = fmt.Sprintf("%s: %s", errCode, desc)
+		}
+		s.render(w, http.StatusBadRequest, "Authentication failed", msg)
+		s.finish(callbackResult{err: withExitCode(ExitAuthRequired, fmt.Errorf("authorization failed: %s", msg))})
+		return
+	}
+
+	code := query.Get("code")
+	if code == "" {
+		s.render(w, http.StatusBadRequest, "Authentication failed", "No authorization code was received.")
+		s.finish(callbackResult{err: fmt.Errorf("no code received in callback")})
+		return
+	}
+
+	s.render(w, http.StatusOK, "Authentication successful", "You can close this window and return to your terminal.")
+	s.finish(callbackResult{code: code})
+}
+
+func (s *callbackServer) render(w http.ResponseWriter, status int, title, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	callbackPage.Execute(w, struct{ Title, Message string }{title, message})
+}
+
+// finish records the first result; later callbacks are ignored
+func (s *callbackServer) finish(result callbackResult) {
+	select {
+	case s.result <- result:
+	default:
+	}
+}
+
+// wait blocks until the callback arrives or the timeout expires
+func (s *callbackServer) wait(timeout time.Duration) (string, error) {
+	select {
+	case result := <-s.result:
+		return result.code, result.err
+	case <-time.After(timeout):
+		return "", withExitCode(ExitAuthRequired, fmt.Errorf("timeout waiting for authentication"))
+	}
+}
+
+// shutdown stops the server, letting in-flight responses reach the browser
+func (s *callbackServer) shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.server.Shutdown(ctx); err != nil {
+		s.server.Close()
+	}
+}
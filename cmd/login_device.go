@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/quicdb/quic-cli/internal/auth"
+	"github.com/quicdb/quic-cli/internal/config"
+	"github.com/quicdb/quic-cli/internal/retry"
+)
+
+// deviceCodeGrantType is the OAuth 2.0 device authorization grant (RFC 8628)
+const deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+// DeviceAuthorizationResponse represents the response from the device authorization endpoint
+type DeviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// deviceTokenError is the error body returned while polling the token endpoint
+type deviceTokenError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// loginDevice performs the device authorization flow for machines without a
+// local browser, such as SSH sessions and dev containers
+func loginDevice(cfg *config.Config) error {
+	device, err := requestDeviceCode(cfg)
+	if err != nil {
+		return fmt.Errorf("error requesting device code: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "To sign in, open %s in a browser on any device and enter the code:\n\n", device.VerificationURI)
+	fmt.Fprintf(os.Stderr, "    %s\n\n", device.UserCode)
+	if device.VerificationURIComplete != "" {
+		fmt.Fprintf(os.Stderr, "Or open this link directly: %s\n\n", device.VerificationURIComplete)
+	}
+	fmt.Fprintln(os.Stderr, "Waiting for authorization...")
+
+	token, err := pollDeviceToken(cfg, device)
+	if err != nil {
+		return err
+	}
+
+	if err := verifyTokenResponse(token); err != nil {
+		return withExitCode(ExitAuthRequired, err)
+	}
+
+	// Save tokens securely to OS keychain/credential manager
+	if err := auth.SaveAccessToken(token.AccessToken, token.ExpiresIn); err != nil {
+		return fmt.Errorf("failed to save access token: %w", err)
+	}
+
+	if token.RefreshToken != "" {
+		if err := auth.SaveToken(token.RefreshToken, auth.RefreshToken); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to save refresh token: %v\n", err)
+		}
+	}
+
+	return nil
+}
+
+// requestDeviceCode starts the flow and returns the codes to show the user
+func requestDeviceCode(cfg *config.Config) (*DeviceAuthorizationResponse, error) {
+	url := fmt.Sprintf("%s/v1/public/%s/oauth2/device_authorization", cfg.StytchURL, cfg.ProjectID)
+
+	body := map[string]string{
+		"client_id": cfg.ClientID,
+		"scope":     "offline_access",
+	}
+
+	status, respBody, err := postJSON(url, body)
+	if err != nil {
+		return nil, err
+	}
+	if status != 200 {
+		return nil, fmt.Errorf("error from API (status %d): %s", status, string(respBody))
+	}
+
+	var device DeviceAuthorizationResponse
+	if err := json.Unmarshal(respBody, &device); err != nil {
+		return nil, fmt.Errorf("error parsing response: %v", err)
+	}
+	if device.DeviceCode == "" || device.UserCode == "" || device.VerificationURI == "" {
+		return nil, fmt.Errorf("incomplete device authorization response: %s", string(respBody))
+	}
+
+	return &device, nil
+}
+
+// pollDeviceToken polls the token endpoint until the user approves or denies
+// the request, honoring the server's interval and slow_down responses
+func pollDeviceToken(cfg *config.Config, device *DeviceAuthorizationResponse) (*TokenResponse, error) {
+	url := fmt.Sprintf("%s/v1/public/%s/oauth2/token", cfg.StytchURL, cfg.ProjectID)
+
+	interval := time.Duration(device.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	expiresIn := time.Duration(device.ExpiresIn) * time.Second
+	if expiresIn <= 0 {
+		expiresIn = 10 * time.Minute
+	}
+	deadline := time.Now().Add(expiresIn)
+
+	body := map[string]string{
+		"client_id":   cfg.ClientID,
+		"device_code": device.DeviceCode,
+		"grant_type":  deviceCodeGrantType,
+	}
+
+	for {
+		time.Sleep(interval)
+		if time.Now().After(deadline) {
+			return nil, withExitCode(ExitAuthRequired, fmt.Errorf("device code expired before authorization completed"))
+		}
+
+		status, respBody, err := postJSON(url, body)
+		if err != nil {
+			return nil, err
+		}
+
+		if status == 200 {
+			var token TokenResponse
+			if err := json.Unmarshal(respBody, &token); err != nil {
+				return nil, fmt.Errorf("error parsing response: %v", err)
+			}
+			return &token, nil
+		}
+
+		var tokenErr deviceTokenError
+		if err := json.Unmarshal(respBody, &tokenErr); err != nil {
+			return nil, fmt.Errorf("error from API (status %d): %s", status, string(respBody))
+		}
+
+		switch tokenErr.Error {
+		case "authorization_pending":
+			// Keep polling
+		case "slow_down":
+			// RFC 8628 section 3.5: increase the interval by 5 seconds
+			interval += 5 * time.Second
+		case "access_denied":
+			return nil, withExitCode(ExitAuthRequired, fmt.Errorf("authorization was denied"))
+		case "expired_token":
+			return nil, withExitCode(ExitAuthRequired, fmt.Errorf("device code expired before authorization completed"))
+		default:
+			return nil, fmt.Errorf("error from API (status %d): %s", status, string(respBody))
+		}
+	}
+}
+
+// postJSON sends a JSON POST request and returns the status and response body
+func postJSON(url string, body map[string]string) (int, []byte, error) {
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("error marshaling request body: %v", err)
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return 0, nil, fmt.Errorf("error creating request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := retry.NewClient(30 * time.Second)
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("error reading response: %v", err)
+	}
+
+	return resp.StatusCode, respBody, nil
+}
@@ -2,17 +2,145 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"strings"
 
 	"github.com/quicdb/quic-cli/internal/auth"
+	"github.com/quicdb/quic-cli/internal/config"
+	"github.com/quicdb/quic-cli/internal/userconfig"
 	"github.com/spf13/cobra"
 )
 
+// logoutResult reports what 'quic logout' did for one profile
+type logoutResult struct {
+	Profile  string   `json:"profile" yaml:"profile"`
+	Revoked  []string `json:"revoked" yaml:"revoked"`
+	Removed  []string `json:"removed" yaml:"removed"`
+	Warnings []string `json:"warnings,omitempty" yaml:"warnings,omitempty"`
+}
+
 var logoutCmd = &cobra.Command{
 	Use:   "logout",
 	Short: "Logout from QuicDB",
-	Run: func(cmd *cobra.Command, args []string) {
-		if err := auth.ClearAllTokens(); err != nil {
-			fmt.Printf("Warning: Failed to logout: %v\n", err)
+	Long: `Logout from QuicDB.
+
+The refresh and access tokens are revoked at the identity provider before they
+are removed from the credential store, so copies of them stop working too.
+Use --local-only to skip revocation, e.g. when offline.`,
+	Args: usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		allProfiles, _ := cmd.Flags().GetBool("all-profiles")
+		localOnly, _ := cmd.Flags().GetBool("local-only")
+
+		profiles := []string{userconfig.Profile()}
+		if allProfiles {
+			settings, err := userconfig.LoadSettings()
+			if err != nil {
+				return fmt.Errorf("failed to load configuration: %w", err)
+			}
+			profiles = settings.ProfileNames()
 		}
+
+		var results []logoutResult
+		for _, profile := range profiles {
+			result, err := logoutProfile(profile, localOnly)
+			if err != nil {
+				return fmt.Errorf("failed to logout of profile '%s': %w", profile, err)
+			}
+			results = append(results, result)
+		}
+
+		if env := auth.LoadEnvCredentials(); env != nil {
+			fmt.Fprintf(os.Stderr, "Note: credentials in %s are still set in the environment\n", envCredentialVars(env))
+		}
+
+		return printOutput(results, func(w io.Writer) error {
+			for _, result := range results {
+				printLogoutResult(w, result)
+			}
+			return nil
+		})
 	},
 }
+
+func init() {
+	logoutCmd.Flags().Bool("all-profiles", false, "Logout of every profile")
+	logoutCmd.Flags().Bool("local-only", false, "Only remove local credentials, without revoking them at the identity provider")
+}
+
+// logoutProfile revokes and removes the stored credentials of a profile.
+// Revocation failures are reported as warnings so a broken network or identity
+// provider never leaves credentials behind on disk.
+func logoutProfile(profile string, localOnly bool) (logoutResult, error) {
+	result := logoutResult{Profile: profile, Revoked: []string{}, Removed: []string{}}
+
+	accessToken, accessErr := auth.LoadProfileToken(profile, auth.AccessToken)
+	refreshToken, refreshErr := auth.LoadProfileToken(profile, auth.RefreshToken)
+	m2mClientID, m2mErr := auth.LoadProfileToken(profile, auth.M2MClientID)
+	m2mClientSecret, _ := auth.LoadProfileToken(profile, auth.M2MClientSecret)
+
+	if !localOnly {
+		// Tokens from an M2M login belong to the M2M client, not the CLI's
+		// public OAuth client
+		clientID, clientSecret := config.Get().ClientID, ""
+		if m2mErr == nil {
+			clientID, clientSecret = m2mClientID, m2mClientSecret
+		}
+
+		// Revoke the refresh token first: it is the long-lived credential
+		if refreshErr == nil {
+			if err := auth.RevokeToken(refreshToken, auth.HintRefreshToken, clientID, clientSecret); err != nil {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("failed to revoke refresh token: %v", err))
+			} else {
+				result.Revoked = append(result.Revoked, "refresh token")
+			}
+		}
+		if accessErr == nil {
+			if err := auth.RevokeToken(accessToken, auth.HintAccessToken, clientID, clientSecret); err != nil {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("failed to revoke access token: %v", err))
+			} else {
+				result.Revoked = append(result.Revoked, "access token")
+			}
+		}
+	}
+
+	if accessErr == nil {
+		result.Removed = append(result.Removed, "access token")
+	}
+	if refreshErr == nil {
+		result.Removed = append(result.Removed, "refresh token")
+	}
+	if m2mErr == nil {
+		result.Removed = append(result.Removed, "M2M credentials")
+	}
+
+	if err := auth.ClearProfileTokens(profile); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+func printLogoutResult(w io.Writer, result logoutResult) {
+	if len(result.Removed) == 0 {
+		fmt.Fprintf(w, "Not logged in to profile '%s', nothing to remove\n", result.Profile)
+		return
+	}
+
+	fmt.Fprintf(w, "Logged out of profile '%s'\n", result.Profile)
+	if len(result.Revoked) > 0 {
+		fmt.Fprintf(w, "  Revoked: %s\n", strings.Join(result.Revoked, ", "))
+	}
+	fmt.Fprintf(w, "  Removed: %s\n", strings.Join(result.Removed, ", "))
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+}
+
+// envCredentialVars names the environment variables providing env
+func envCredentialVars(env *auth.EnvCredentials) string {
+	if env.IsM2M() {
+		return auth.EnvClientID + "/" + auth.EnvClientSecret
+	}
+	return auth.EnvAccessToken
+}
@@ -3,45 +3,230 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/quicdb/quic-cli/internal/api"
 	"github.com/quicdb/quic-cli/internal/auth"
+	"github.com/quicdb/quic-cli/internal/cluster"
 	"github.com/spf13/cobra"
 )
 
 var lsCmd = &cobra.Command{
 	Use:   "ls",
 	Short: "List all database branches",
-	Args:  cobra.NoArgs,
-	Run: func(cmd *cobra.Command, args []string) {
+	Args:  usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
 		// Check if user is authenticated
-		_, err := auth.LoadToken(auth.AccessToken)
+		if err := requireLogin(); err != nil {
+			return err
+		}
+
+		filter, err := parseBranchFilter(cmd)
 		if err != nil {
-			fmt.Println("You are not logged in. Please run 'quic login' first.")
-			return
+			return newUsageError(err)
 		}
 
 		client := api.NewClient()
 		ctx := context.Background()
 
-		branches, err := client.ListBranches(ctx)
+		// Scope to a single cluster if requested
+		opts := api.ListBranchesOptions{}
+		if filter.cluster != "" {
+			clusters, err := client.ListClusters(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to list clusters: %w", err)
+			}
+			c, err := cluster.Match(clusters, filter.cluster)
+			if err != nil {
+				return err
+			}
+			opts.ClusterID = c.ID
+			filter.clusterName = c.Name
+		}
+
+		// Resolve "me" to the subject of the current access token
+		if filter.createdBy == "me" {
+			token, err := client.AccessToken()
+			if err != nil {
+				return err
+			}
+			claims, err := auth.ParseClaims(token)
+			if err != nil {
+				return fmt.Errorf("failed to determine current user: %w", err)
+			}
+			filter.createdBy = claims.Subject
+		}
+		opts.CreatedBy = filter.createdBy
+
+		branches, err := client.ListBranches(ctx, opts)
 		if err != nil {
-			fmt.Printf("Failed to list branches: %v\n", err)
-			return
+			return fmt.Errorf("failed to list branches: %w", err)
+		}
+
+		// Apply filters client-side as well in case the API ignored them
+		branches = filter.apply(branches)
+
+		return printOutput(branches, func(w io.Writer) error {
+			if len(branches) == 0 {
+				fmt.Fprintln(w, "No branches found. Create one with 'quic checkout <branch-name>'")
+				return nil
+			}
+
+			// Print table header
+			fmt.Fprintf(w, "  %-20s %-30s %-30s %-20s\n", "Branch", "Cluster", "Created by", "Created at")
+			fmt.Fprintf(w, "  %-20s %-30s %-30s %-20s\n", "--------------------", "------------------------------", "------------------------------", "--------------------")
+
+			// Print each branch
+			for _, branch := range branches {
+				fmt.Fprintf(w, "  %-20s %-30s %-30s %-20s\n", branch.Name, branch.Cluster, branch.CreatedBy, branch.CreatedAt)
+			}
+			return nil
+		})
+	},
+}
+
+func init() {
+	lsCmd.Flags().StringP("cluster", "c", "", "Only list branches of this cluster (ID, name, subdomain or unique ID prefix)")
+	lsCmd.Flags().String("created-by", "", "Only list branches created by this user ('me' for yourself)")
+	lsCmd.Flags().String("since", "", "Only list branches created within this duration, e.g. 2h or 7d")
+	lsCmd.Flags().String("older-than", "", "Only list branches created longer ago than this duration, e.g. 30d")
+	lsCmd.Flags().String("name", "", "Only list branches whose name matches this glob, e.g. 'pr-*'")
+	lsCmd.Flags().String("sort", "", "Sort by name, created or cluster (default: API order)")
+	lsCmd.Flags().Int("limit", 0, "Maximum number of branches to list (0 for no limit)")
+}
+
+// branchFilter holds the parsed 'quic ls' filtering options
+type branchFilter struct {
+	cluster     string
+	clusterName string
+	createdBy   string
+	since       time.Duration
+	olderThan   time.Duration
+	name        string
+	sort        string
+	limit       int
+}
+
+func parseBranchFilter(cmd *cobra.Command) (*branchFilter, error) {
+	f := &branchFilter{}
+	f.cluster, _ = cmd.Flags().GetString("cluster")
+	f.createdBy, _ = cmd.Flags().GetString("created-by")
+	f.name, _ = cmd.Flags().GetString("name")
+	f.sort, _ = cmd.Flags().GetString("sort")
+	f.limit, _ = cmd.Flags().GetInt("limit")
+
+	var err error
+	since, _ := cmd.Flags().GetString("since")
+	if f.since, err = parseAge(since); err != nil {
+		return nil, fmt.Errorf("invalid --since: %w", err)
+	}
+	olderThan, _ := cmd.Flags().GetString("older-than")
+	if f.olderThan, err = parseAge(olderThan); err != nil {
+		return nil, fmt.Errorf("invalid --older-than: %w", err)
+	}
+
+	if f.name != "" {
+		if _, err := path.Match(f.name, ""); err != nil {
+			return nil, fmt.Errorf("invalid --name pattern: %w", err)
 		}
+	}
+
+	switch f.sort {
+	case "", "name", "created", "cluster":
+	default:
+		return nil, fmt.Errorf("invalid --sort %q (expected name, created or cluster)", f.sort)
+	}
+
+	if f.limit < 0 {
+		return nil, fmt.Errorf("--limit must not be negative")
+	}
 
-		if len(branches) == 0 {
-			fmt.Println("No branches found. Create one with 'quic checkout <branch-name>'")
-			return
+	return f, nil
+}
+
+// apply filters, sorts and truncates branches
+func (f *branchFilter) apply(branches []api.Branch) []api.Branch {
+	now := time.Now()
+
+	result := []api.Branch{}
+	for _, b := range branches {
+		if f.clusterName != "" && b.Cluster != f.clusterName {
+			continue
+		}
+		if f.createdBy != "" && !strings.EqualFold(b.CreatedBy, f.createdBy) {
+			continue
 		}
+		if f.name != "" {
+			if ok, _ := path.Match(f.name, b.Name); !ok {
+				continue
+			}
+		}
+		if f.since > 0 || f.olderThan > 0 {
+			createdAt, err := time.Parse(time.RFC3339, b.CreatedAt)
+			if err != nil {
+				continue
+			}
+			age := now.Sub(createdAt)
+			if f.since > 0 && age > f.since {
+				continue
+			}
+			if f.olderThan > 0 && age < f.olderThan {
+				continue
+			}
+		}
+		result = append(result, b)
+	}
+
+	switch f.sort {
+	case "name":
+		sort.SliceStable(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	case "created":
+		sort.SliceStable(result, func(i, j int) bool {
+			ti, erri := time.Parse(time.RFC3339, result[i].CreatedAt)
+			tj, errj := time.Parse(time.RFC3339, result[j].CreatedAt)
+			if erri != nil || errj != nil {
+				return result[i].CreatedAt < result[j].CreatedAt
+			}
+			return ti.Before(tj)
+		})
+	case "cluster":
+		sort.SliceStable(result, func(i, j int) bool { return result[i].Cluster < result[j].Cluster })
+	}
+
+	if f.limit > 0 && len(result) > f.limit {
+		result = result[:f.limit]
+	}
 
-		// Print table header
-		fmt.Printf("  %-20s %-30s %-30s %-20s\n", "Branch", "Cluster", "Created by", "Created at")
-		fmt.Printf("  %-20s %-30s %-30s %-20s\n", "--------------------", "------------------------------", "------------------------------", "--------------------")
+	return result
+}
+
+// parseAge parses a duration, additionally accepting days (7d) and weeks (2w)
+func parseAge(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
 
-		// Print each branch
-		for _, branch := range branches {
-			fmt.Printf("  %-20s %-30s %-30s %-20s\n", branch.Name, branch.Cluster, branch.CreatedBy, branch.CreatedAt)
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil || count < 0 {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			return time.Duration(count) * unit, nil
 		}
-	},
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
 }
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/quicdb/quic-cli/internal/cluster"
+	"github.com/quicdb/quic-cli/internal/output"
+)
+
+// outputFlag holds the raw value of the global --output flag
+var outputFlag string
+
+// outputFormat returns the output format selected with --output
+func outputFormat() output.Format {
+	format, err := output.ParseFormat(outputFlag)
+	if err != nil {
+		return output.Table
+	}
+	return format
+}
+
+// printOutput renders v to stdout in the selected output format, falling back
+// to the table function for the default human-readable output
+func printOutput(v any, table func(w io.Writer) error) error {
+	return output.Print(os.Stdout, outputFormat(), v, table)
+}
+
+// printError reports a failed command on stderr. When a machine-readable
+// format is selected and the failure is a cluster selection prompt, the
+// candidate clusters are also rendered to stdout so scripts can pick one.
+func printError(err error) {
+	var selErr *cluster.SelectionError
+	if outputFormat() != output.Table && errors.As(err, &selErr) {
+		printOutput(selErr.Clusters, nil)
+	}
+
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+
+	if exitCode(err) == ExitUsage {
+		fmt.Fprintln(os.Stderr, "Run 'quic --help' for usage.")
+	}
+}
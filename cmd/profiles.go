@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/quicdb/quic-cli/internal/auth"
+	"github.com/quicdb/quic-cli/internal/userconfig"
+	"github.com/spf13/cobra"
+)
+
+// profileFlag holds the raw value of the global --profile flag
+var profileFlag string
+
+// profileListItem is a profile as rendered by 'quic profiles ls'
+type profileListItem struct {
+	Name            string `json:"name" yaml:"name"`
+	Current         bool   `json:"current" yaml:"current"`
+	LoggedIn        bool   `json:"logged_in" yaml:"logged_in"`
+	SelectedCluster string `json:"selected_cluster,omitempty" yaml:"selected_cluster,omitempty"`
+}
+
+// applyProfile selects the profile for this invocation from --profile or
+// QUIC_PROFILE; without either the stored current profile is used
+func applyProfile() error {
+	name := profileFlag
+	if name == "" {
+		name = os.Getenv("QUIC_PROFILE")
+	}
+	if err := userconfig.SetProfile(name); err != nil {
+		return newUsageError(err)
+	}
+	return nil
+}
+
+var profilesCmd = &cobra.Command{
+	Use:   "profiles",
+	Short: "Manage login profiles",
+	Long: `Manage login profiles.
+
+Each profile has its own credentials and selected cluster, so you can stay
+logged in to several organizations at once. Create a profile by logging in to
+it with 'quic login --profile <name>', then pick it per command with --profile
+or QUIC_PROFILE, or make it the default with 'quic profiles use <name>'.`,
+}
+
+var profilesLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List profiles",
+	Args:  usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		settings, err := userconfig.LoadSettings()
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+
+		current := userconfig.Profile()
+		var items []profileListItem
+		for _, name := range settings.ProfileNames() {
+			_, err := auth.LoadProfileToken(name, auth.AccessToken)
+			items = append(items, profileListItem{
+				Name:            name,
+				Current:         name == current,
+				LoggedIn:        err == nil,
+				SelectedCluster: settings.ProfileConfig(name).SelectedCluster,
+			})
+		}
+
+		return printOutput(items, func(w io.Writer) error {
+			fmt.Fprintf(w, "  %-20s %-10s %-36s\n", "Profile", "Logged in", "Selected cluster")
+			fmt.Fprintf(w, "  %-20s %-10s %-36s\n", "--------------------", "----------", "------------------------------------")
+			for _, item := range items {
+				marker := " "
+				if item.Current {
+					marker = "*"
+				}
+				fmt.Fprintf(w, "%s %-20s %-10s %-36s\n", marker, item.Name, yesNo(item.LoggedIn), item.SelectedCluster)
+			}
+			return nil
+		})
+	},
+}
+
+// profileResult is a changed profile as rendered by 'quic profiles use' and
+// 'quic profiles rm'
+type profileResult struct {
+	Profile string `json:"profile" yaml:"profile"`
+	Current bool   `json:"current,omitempty" yaml:"current,omitempty"`
+	Removed bool   `json:"removed,omitempty" yaml:"removed,omitempty"`
+}
+
+var profilesUseCmd = &cobra.Command{
+	Use:   "use <profile>",
+	Short: "Set the default profile",
+	Args:  usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		if err := userconfig.ValidateProfileName(name); err != nil {
+			return newUsageError(err)
+		}
+
+		exists, err := userconfig.ProfileExists(name)
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+		if !exists {
+			return withExitCode(ExitNotFound, fmt.Errorf("profile '%s' does not exist. Run 'quic login --profile %s' to create it", name, name))
+		}
+
+		if err := userconfig.UseProfile(name); err != nil {
+			return fmt.Errorf("failed to set profile: %w", err)
+		}
+
+		result := profileResult{Profile: name, Current: true}
+		return printOutput(result, func(w io.Writer) error {
+			_, err := fmt.Fprintf(w, "Default profile set to: %s\n", name)
+			return err
+		})
+	},
+}
+
+var profilesRmCmd = &cobra.Command{
+	Use:   "rm <profile>",
+	Short: "Remove a profile and its stored credentials",
+	Args:  usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		if name == userconfig.DefaultProfile {
+			return newUsageError(fmt.Errorf("the default profile cannot be removed; use 'quic logout' to clear its credentials"))
+		}
+		if err := userconfig.ValidateProfileName(name); err != nil {
+			return newUsageError(err)
+		}
+
+		exists, err := userconfig.ProfileExists(name)
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+		if !exists {
+			return withExitCode(ExitNotFound, fmt.Errorf("profile '%s' does not exist", name))
+		}
+
+		if err := auth.ClearProfileTokens(name); err != nil {
+			return fmt.Errorf("failed to remove credentials of profile '%s': %w", name, err)
+		}
+		if err := userconfig.RemoveProfile(name); err != nil {
+			return fmt.Errorf("failed to remove profile: %w", err)
+		}
+
+		result := profileResult{Profile: name, Removed: true}
+		return printOutput(result, func(w io.Writer) error {
+			_, err := fmt.Fprintf(w, "Removed profile: %s\n", name)
+			return err
+		})
+	},
+}
+
+func init() {
+	profilesCmd.AddCommand(profilesLsCmd)
+	profilesCmd.AddCommand(profilesUseCmd)
+	profilesCmd.AddCommand(profilesRmCmd)
+}
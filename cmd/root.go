@@ -4,8 +4,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/quicdb/quic-cli/internal/output"
+	"github.com/quicdb/quic-cli/internal/retry"
 	"github.com/quicdb/quic-cli/releases"
 	"github.com/spf13/cobra"
 )
@@ -13,19 +17,49 @@ import (
 var rootCmd = &cobra.Command{
 	Use:   "quic",
 	Short: "QuicDB CLI",
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+	Long: `QuicDB CLI
+
+Exit codes:
+  0  success
+  1  unclassified error
+  2  invalid arguments or flags
+  3  authentication required (run 'quic login')
+  4  resource not found
+  5  conflict, e.g. branch already exists or cluster not ready
+  6  network error, timeout, rate limiting or unavailability reaching the API`,
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := output.ParseFormat(outputFlag); err != nil {
+			return newUsageError(err)
+		}
+		if err := applyProfile(); err != nil {
+			return err
+		}
+		if err := applyRetryPolicy(cmd); err != nil {
+			return err
+		}
 		checkForUpdateNotification()
+		return nil
 	},
 }
 
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		printError(err)
+		os.Exit(exitCode(err))
 	}
 }
 
 func init() {
+	rootCmd.PersistentFlags().StringVarP(&outputFlag, "output", "o", string(output.Table), "Output format: table, json or yaml")
+	rootCmd.PersistentFlags().StringVar(&profileFlag, "profile", "", "Profile to use (default is the current profile, overridable with QUIC_PROFILE)")
+	rootCmd.PersistentFlags().Int("max-retries", retry.DefaultPolicy().MaxRetries, "Retries for transient API failures, 0 to disable (env QUIC_MAX_RETRIES)")
+	rootCmd.PersistentFlags().Duration("retry-timeout", retry.DefaultPolicy().Timeout, "Stop retrying API requests after this long (env QUIC_RETRY_TIMEOUT)")
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return newUsageError(err)
+	})
+
 	rootCmd.AddCommand(loginCmd)
 	rootCmd.AddCommand(logoutCmd)
 	rootCmd.AddCommand(updateCmd)
@@ -35,6 +69,52 @@ func init() {
 	rootCmd.AddCommand(lsCmd)
 	rootCmd.AddCommand(dashCmd)
 	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(clustersCmd)
+	rootCmd.AddCommand(branchCmd)
+	rootCmd.AddCommand(urlCmd)
+	rootCmd.AddCommand(profilesCmd)
+	rootCmd.AddCommand(authCmd)
+	rootCmd.AddCommand(whoamiCmd)
+}
+
+// applyRetryPolicy configures retries of HTTP requests from --max-retries and
+// --retry-timeout, falling back to QUIC_MAX_RETRIES and QUIC_RETRY_TIMEOUT
+func applyRetryPolicy(cmd *cobra.Command) error {
+	policy := retry.DefaultPolicy()
+	flags := cmd.Flags()
+
+	maxRetries, _ := flags.GetInt("max-retries")
+	if !flags.Changed("max-retries") {
+		if env := os.Getenv("QUIC_MAX_RETRIES"); env != "" {
+			n, err := strconv.Atoi(env)
+			if err != nil {
+				return newUsageError(fmt.Errorf("invalid QUIC_MAX_RETRIES %q", env))
+			}
+			maxRetries = n
+		}
+	}
+	if maxRetries < 0 {
+		return newUsageError(fmt.Errorf("max retries must not be negative"))
+	}
+	policy.MaxRetries = maxRetries
+
+	timeout, _ := flags.GetDuration("retry-timeout")
+	if !flags.Changed("retry-timeout") {
+		if env := os.Getenv("QUIC_RETRY_TIMEOUT"); env != "" {
+			d, err := time.ParseDuration(env)
+			if err != nil {
+				return newUsageError(fmt.Errorf("invalid QUIC_RETRY_TIMEOUT %q", env))
+			}
+			timeout = d
+		}
+	}
+	if timeout <= 0 {
+		return newUsageError(fmt.Errorf("retry timeout must be positive"))
+	}
+	policy.Timeout = timeout
+
+	retry.SetPolicy(policy)
+	return nil
 }
 
 func checkForUpdateNotification() {
@@ -44,11 +124,12 @@ func checkForUpdateNotification() {
 	}
 
 	if releases.IsNewerVersion(releases.Version, latest) {
-		fmt.Printf("> Newer version available: %s -> %s\n", releases.Version, latest)
+		// Written to stderr so it never mixes with machine-readable output
+		fmt.Fprintf(os.Stderr, "> Newer version available: %s -> %s\n", releases.Version, latest)
 		if isHomebrewInstall() {
-			fmt.Println("> $ brew update && brew upgrade quic")
+			fmt.Fprintln(os.Stderr, "> $ brew update && brew upgrade quic")
 		} else {
-			fmt.Println("> $ quic update")
+			fmt.Fprintln(os.Stderr, "> $ quic update")
 		}
 	}
 }
@@ -8,6 +8,7 @@ import (
 	"runtime"
 	"time"
 
+	"github.com/quicdb/quic-cli/internal/retry"
 	"github.com/quicdb/quic-cli/releases"
 	"github.com/spf13/cobra"
 )
@@ -15,16 +16,12 @@ import (
 var updateCmd = &cobra.Command{
 	Use:   "update",
 	Short: "Update to the latest version",
-	Run: func(cmd *cobra.Command, args []string) {
+	Args:  usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
 		if isHomebrewInstall() {
-			fmt.Println("Detected Homebrew installation.")
-			fmt.Println("Please use: brew update && brew upgrade quic")
-			os.Exit(1)
-		}
-		if err := selfUpdate(); err != nil {
-			fmt.Printf("Error: %v\n", err)
-			os.Exit(1)
+			return fmt.Errorf("detected Homebrew installation. Please use: brew update && brew upgrade quic")
 		}
+		return selfUpdate()
 	},
 }
 
@@ -54,7 +51,7 @@ func selfUpdate() error {
 
 	downloadURL := fmt.Sprintf("https://github.com/quicdb/quic-cli/releases/latest/download/%s", binaryName)
 
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := retry.NewClient(30 * time.Second)
 	req, err := http.NewRequest("GET", downloadURL, nil)
 	if err != nil {
 		return err
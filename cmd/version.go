@@ -10,6 +10,7 @@ import (
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Show current version",
+	Args:  usageArgs(cobra.NoArgs),
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println(releases.Version)
 	},
@@ -2,7 +2,13 @@ module github.com/quicdb/quic-cli
 
 go 1.24
 
-require github.com/spf13/cobra v1.10.1
+require (
+	github.com/spf13/cobra v1.10.1
+	github.com/zalando/go-keyring v0.2.6
+	golang.org/x/sys v0.26.0
+	golang.org/x/term v0.25.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	al.essio.dev/pkg/shellescape v1.5.1 // indirect
@@ -10,6 +16,4 @@ require (
 	github.com/godbus/dbus/v5 v5.1.0 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/spf13/pflag v1.0.9 // indirect
-	github.com/zalando/go-keyring v0.2.6 // indirect
-	golang.org/x/sys v0.26.0 // indirect
 )
@@ -7,15 +7,21 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/quicdb/quic-cli/internal/auth"
 	"github.com/quicdb/quic-cli/internal/config"
+	"github.com/quicdb/quic-cli/internal/retry"
 )
 
 type Client struct {
 	httpClient *http.Client
 	baseURL    string
+
+	// Credentials from the environment; when set the keyring is not used
+	env      *auth.EnvCredentials
+	envToken string // access token exchanged from env M2M credentials
 }
 
 type APIError struct {
@@ -32,38 +38,92 @@ type CreateBranchRequest struct {
 }
 
 type CreateBranchResponse struct {
-	User     string `json:"user"`
-	Password string `json:"password"`
-	Host     string `json:"host"`
-	Port     int    `json:"port"`
-	Database string `json:"database"`
+	User     string `json:"user" yaml:"user"`
+	Password string `json:"password" yaml:"password"`
+	Host     string `json:"host" yaml:"host"`
+	Port     int    `json:"port" yaml:"port"`
+	Database string `json:"database" yaml:"database"`
 }
 
 type Cluster struct {
-	ID               string `json:"id"`
-	Name             string `json:"name"`
-	Subdomain        string `json:"subdomain"`
-	Region           string `json:"region"`
-	SelectedDatabase string `json:"selected_database"`
+	ID               string `json:"id" yaml:"id"`
+	Name             string `json:"name" yaml:"name"`
+	Subdomain        string `json:"subdomain" yaml:"subdomain"`
+	Region           string `json:"region" yaml:"region"`
+	SelectedDatabase string `json:"selected_database" yaml:"selected_database"`
 }
 
 type Branch struct {
-	ID        string `json:"id"`
-	Name      string `json:"name"`
-	Cluster   string `json:"cluster"`    // Cluster.Name
-	CreatedBy string `json:"created_by"` // CreatedByID
-	CreatedAt string `json:"created_at"`
+	ID        string `json:"id" yaml:"id"`
+	Name      string `json:"name" yaml:"name"`
+	Cluster   string `json:"cluster" yaml:"cluster"`       // Cluster.Name
+	CreatedBy string `json:"created_by" yaml:"created_by"` // CreatedByID
+	CreatedAt string `json:"created_at" yaml:"created_at"`
+}
+
+// ClusterStatus reports whether a cluster can accept new branches
+type ClusterStatus struct {
+	ID     string `json:"id" yaml:"id"`
+	Status string `json:"status" yaml:"status"`
+	Ready  bool   `json:"ready" yaml:"ready"`
+}
+
+// BranchDetails describes an existing branch together with its connection info
+type BranchDetails struct {
+	Branch               `yaml:",inline"`
+	CreateBranchResponse `yaml:",inline"`
 }
 
 func NewClient() *Client {
 	cfg := config.Get()
 
 	return &Client{
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second, // Default timeout
-		},
-		baseURL: cfg.APIURL,
+		httpClient: retry.NewClient(30 * time.Second), // Default timeout per attempt
+		baseURL:    cfg.APIURL,
+		env:        auth.LoadEnvCredentials(),
+	}
+}
+
+// AccessToken returns a usable access token. Environment credentials take
+// precedence; M2M credentials from the environment are exchanged once per
+// client and kept in memory only.
+func (c *Client) AccessToken() (string, error) {
+	if c.env == nil {
+		return auth.ValidAccessToken()
+	}
+
+	if !c.env.IsM2M() {
+		return c.env.AccessToken, nil
+	}
+
+	if c.envToken == "" {
+		if err := c.exchangeEnvCredentials(); err != nil {
+			return "", err
+		}
+	}
+	return c.envToken, nil
+}
+
+// refreshAccessToken obtains a new access token after staleToken was rejected
+func (c *Client) refreshAccessToken(staleToken string) error {
+	if c.env == nil {
+		return auth.RefreshAccessToken(staleToken)
+	}
+
+	if !c.env.IsM2M() {
+		return fmt.Errorf("the token in %s was rejected", auth.EnvAccessToken)
 	}
+	return c.exchangeEnvCredentials()
+}
+
+func (c *Client) exchangeEnvCredentials() error {
+	cfg := config.Get()
+	tokenResp, err := ExchangeM2MToken(c.env.ClientID, c.env.ClientSecret, cfg.StytchURL, cfg.ProjectID)
+	if err != nil {
+		return fmt.Errorf("failed to exchange %s/%s: %w", auth.EnvClientID, auth.EnvClientSecret, err)
+	}
+	c.envToken = tokenResp.AccessToken
+	return nil
 }
 
 func (c *Client) CreateBranch(ctx context.Context, clusterID, branchName string, timeout time.Duration) (*CreateBranchResponse, error) {
@@ -87,10 +147,8 @@ func (c *Client) CreateBranch(ctx context.Context, clusterID, branchName string,
 	// Set headers
 	req.Header.Set("Content-Type", "application/json")
 
-	// Create a client with the specified timeout
-	client := &http.Client{
-		Timeout: timeout,
-	}
+	// Create a client with the specified timeout per attempt
+	client := retry.NewClient(timeout)
 
 	// Make authenticated request with retry
 	body, err := c.makeAuthenticatedRequest(client, req)
@@ -107,6 +165,29 @@ func (c *Client) CreateBranch(ctx context.Context, clusterID, branchName string,
 	return &branchResp, nil
 }
 
+func (c *Client) GetBranch(ctx context.Context, clusterID, branchName string) (*BranchDetails, error) {
+	// Create request
+	url := fmt.Sprintf("%s/clusters/%s/branches/%s", c.baseURL, clusterID, branchName)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Make authenticated request with retry
+	body, err := c.makeAuthenticatedRequest(c.httpClient, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse successful response
+	var branch BranchDetails
+	if err := json.Unmarshal(body, &branch); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return &branch, nil
+}
+
 func (c *Client) DeleteBranch(ctx context.Context, clusterID, branchName string) error {
 	// Create request
 	url := fmt.Sprintf("%s/clusters/%s/branches/%s", c.baseURL, clusterID, branchName)
@@ -153,9 +234,51 @@ func (c *Client) ListClusters(ctx context.Context) ([]Cluster, error) {
 	return clusters, nil
 }
 
-func (c *Client) ListBranches(ctx context.Context) ([]Branch, error) {
+// ListBranchesOptions narrows the branches returned by ListBranches. Empty
+// fields are not sent.
+type ListBranchesOptions struct {
+	ClusterID string
+	CreatedBy string
+}
+
+func (c *Client) GetClusterStatus(ctx context.Context, clusterID string) (*ClusterStatus, error) {
+	// Create request
+	url := fmt.Sprintf("%s/clusters/%s/status", c.baseURL, clusterID)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Make authenticated request with retry
+	body, err := c.makeAuthenticatedRequest(c.httpClient, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse successful response
+	var status ClusterStatus
+	if err := json.Unmarshal(body, &status); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return &status, nil
+}
+
+func (c *Client) ListBranches(ctx context.Context, opts ListBranchesOptions) ([]Branch, error) {
+	// Build query for server-side filtering
+	query := url.Values{}
+	if opts.ClusterID != "" {
+		query.Set("cluster_id", opts.ClusterID)
+	}
+	if opts.CreatedBy != "" {
+		query.Set("created_by", opts.CreatedBy)
+	}
+
 	// Create request
 	url := fmt.Sprintf("%s/branches", c.baseURL)
+	if len(query) > 0 {
+		url += "?" + query.Encode()
+	}
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -190,10 +313,10 @@ func (c *Client) makeAuthenticatedRequest(client *http.Client, req *http.Request
 		req.Body = io.NopCloser(bytes.NewBuffer(reqBody))
 	}
 
-	// Try with current access token first
-	token, err := auth.LoadToken(auth.AccessToken)
+	// Use the current access token, refreshed ahead of expiry if needed
+	token, err := c.AccessToken()
 	if err != nil {
-		return nil, fmt.Errorf("no valid authentication token found. Please run 'quic login' first")
+		return nil, err
 	}
 
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
@@ -214,12 +337,15 @@ func (c *Client) makeAuthenticatedRequest(client *http.Client, req *http.Request
 	// If we get 401 Unauthorized, try to refresh the token and retry once
 	if resp.StatusCode == 401 {
 		// Attempt to refresh the access token
-		if refreshErr := auth.RefreshAccessToken(); refreshErr != nil {
-			return nil, fmt.Errorf("authentication failed and token refresh failed: %w", refreshErr)
+		if refreshErr := c.refreshAccessToken(token); refreshErr != nil {
+			return nil, &APIError{
+				StatusCode: resp.StatusCode,
+				Message:    fmt.Sprintf("authentication failed and token refresh failed: %v", refreshErr),
+			}
 		}
 
 		// Get the new access token
-		newToken, err := auth.LoadToken(auth.AccessToken)
+		newToken, err := c.AccessToken()
 		if err != nil {
 			return nil, fmt.Errorf("failed to load refreshed token: %w", err)
 		}
@@ -265,7 +391,10 @@ func (c *Client) makeAuthenticatedRequest(client *http.Client, req *http.Request
 				}
 			}
 		}
-		return nil, fmt.Errorf("API error: HTTP %d - %s", resp.StatusCode, string(body))
+		return nil, &APIError{
+			StatusCode: resp.StatusCode,
+			Message:    fmt.Sprintf("API error: HTTP %d - %s", resp.StatusCode, string(body)),
+		}
 	}
 
 	return body, nil
@@ -294,8 +423,8 @@ func ExchangeM2MToken(clientID, clientSecret, stytchURL, projectID string) (*M2M
 		return nil, fmt.Errorf("error marshaling request body: %v", err)
 	}
 
-	// Create the request
-	req, err := http.NewRequest("POST", tokenURL, bytes.NewBuffer(jsonBody))
+	// Create the request; the client credentials grant is safe to repeat
+	req, err := http.NewRequestWithContext(retry.Idempotent(context.Background()), "POST", tokenURL, bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %v", err)
 	}
@@ -304,7 +433,7 @@ func ExchangeM2MToken(clientID, clientSecret, stytchURL, projectID string) (*M2M
 	req.Header.Set("Content-Type", "application/json")
 
 	// Send the request
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := retry.NewClient(30 * time.Second)
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("error making request: %v", err)
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Claims holds the JWT claims of an access token that the CLI relies on
+type Claims struct {
+	Subject      string             `json:"sub"`
+	Issuer       string             `json:"iss"`
+	Audience     Audience           `json:"aud"`
+	ExpiresAt    int64              `json:"exp"`
+	NotBefore    int64              `json:"nbf"`
+	Scope        string             `json:"scope"`
+	Organization *OrganizationClaim `json:"https://stytch.com/organization"`
+}
+
+// Audience is the aud claim, which may be a single string or a list
+type Audience []string
+
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("aud must be a string or a list of strings")
+	}
+	*a = list
+	return nil
+}
+
+// OrganizationClaim identifies the organization a token was issued for
+type OrganizationClaim struct {
+	ID   string `json:"organization_id"`
+	Slug string `json:"slug"`
+}
+
+// Scopes returns the space-separated scope claim as a list
+func (c *Claims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// Org returns the organization slug, or its ID if the token has no slug
+func (c *Claims) Org() string {
+	if c.Organization == nil {
+		return ""
+	}
+	if c.Organization.Slug != "" {
+		return c.Organization.Slug
+	}
+	return c.Organization.ID
+}
+
+// ParseClaims decodes the claims of a JWT without verifying its signature.
+// Only use the result for display and filtering, never for trust decisions.
+func ParseClaims(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("token is not a JWT")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode token payload: %w", err)
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("failed to parse token claims: %w", err)
+	}
+
+	return &claims, nil
+}
@@ -0,0 +1,40 @@
+package auth
+
+import "os"
+
+// Environment variables that supply credentials directly, for CI runners and
+// containers without a keyring. When set, the keyring is never touched.
+const (
+	EnvAccessToken  = "QUIC_ACCESS_TOKEN"
+	EnvClientID     = "QUIC_CLIENT_ID"
+	EnvClientSecret = "QUIC_CLIENT_SECRET"
+)
+
+// EnvCredentials holds credentials read from the environment. Either
+// AccessToken or both ClientID and ClientSecret are set.
+type EnvCredentials struct {
+	AccessToken  string
+	ClientID     string
+	ClientSecret string
+}
+
+// IsM2M reports whether the credentials must be exchanged for an access token
+func (c *EnvCredentials) IsM2M() bool {
+	return c.AccessToken == ""
+}
+
+// LoadEnvCredentials returns the credentials set in the environment, or nil
+// if there are none. QUIC_ACCESS_TOKEN takes precedence over M2M credentials.
+func LoadEnvCredentials() *EnvCredentials {
+	if token := os.Getenv(EnvAccessToken); token != "" {
+		return &EnvCredentials{AccessToken: token}
+	}
+
+	clientID := os.Getenv(EnvClientID)
+	clientSecret := os.Getenv(EnvClientSecret)
+	if clientID != "" && clientSecret != "" {
+		return &EnvCredentials{ClientID: clientID, ClientSecret: clientSecret}
+	}
+
+	return nil
+}
@@ -0,0 +1,285 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/quicdb/quic-cli/internal/userconfig"
+)
+
+// EnvCredentialsPassphrase supplies the passphrase for the encrypted file
+// store. Without it a key derived from machine and user identifiers is used,
+// which protects against copying the file elsewhere but not against other
+// processes of the same user.
+const EnvCredentialsPassphrase = "QUIC_CREDENTIALS_PASSPHRASE"
+
+// pbkdf2Iterations follows the OWASP recommendation for PBKDF2-HMAC-SHA256
+const pbkdf2Iterations = 600000
+
+// credentialsLock serializes read-modify-write cycles on the credentials file
+// across processes, so concurrent updates are not lost
+const credentialsLock = "credentials.lock"
+
+// FileStore keeps credentials in an AES-256-GCM encrypted file under the
+// config directory
+type FileStore struct {
+	path       string
+	passphrase string
+
+	mu   sync.Mutex
+	salt []byte
+	key  []byte // derived from passphrase and salt, cached per process
+}
+
+// UnreadableError is returned when the credentials file exists but cannot be
+// decrypted or parsed, typically because the passphrase changed. Set replaces
+// such a file and Delete removes it, so logging in or out recovers.
+type UnreadableError struct {
+	Path string
+	Err  error
+}
+
+func (e *UnreadableError) Error() string {
+	return fmt.Sprintf("cannot read credentials file %s: %v; run 'quic login' to replace it or 'quic logout' to remove it", e.Path, e.Err)
+}
+
+func (e *UnreadableError) Unwrap() error {
+	return e.Err
+}
+
+// encryptedFile is the on-disk layout of the credentials file
+type encryptedFile struct {
+	Version int    `json:"version"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// NewFileStore returns a file store at ~/.config/quic/credentials.enc
+func NewFileStore() (*FileStore, error) {
+	dir, err := userconfig.Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	passphrase := os.Getenv(EnvCredentialsPassphrase)
+	if passphrase == "" {
+		passphrase = machinePassphrase()
+	}
+
+	return &FileStore{
+		path:       filepath.Join(dir, "credentials.enc"),
+		passphrase: passphrase,
+	}, nil
+}
+
+func (s *FileStore) Get(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values, err := s.read()
+	if err != nil {
+		return "", err
+	}
+	value, ok := values[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+func (s *FileStore) Set(key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := lockFile(credentialsLock, lockTimeout)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	values, err := s.read()
+	var unreadable *UnreadableError
+	if errors.As(err, &unreadable) {
+		// The old credentials are lost either way; starting over lets a new
+		// login succeed
+		fmt.Fprintf(os.Stderr, "Warning: Replacing unreadable credentials file %s\n", s.path)
+		values, err = make(map[string]string), nil
+	}
+	if err != nil {
+		return err
+	}
+	values[key] = value
+	return s.write(values)
+}
+
+func (s *FileStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := lockFile(credentialsLock, lockTimeout)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	values, err := s.read()
+	var unreadable *UnreadableError
+	if errors.As(err, &unreadable) {
+		if err := os.Remove(s.path); err != nil {
+			return fmt.Errorf("failed to remove unreadable credentials file: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: Removed unreadable credentials file %s\n", s.path)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, ok := values[key]; !ok {
+		return ErrNotFound
+	}
+	delete(values, key)
+	return s.write(values)
+}
+
+// read decrypts the credentials file. A missing file is an empty store.
+func (s *FileStore) read() (map[string]string, error) {
+	values := make(map[string]string)
+
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return values, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials file: %w", err)
+	}
+
+	var file encryptedFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, &UnreadableError{Path: s.path, Err: fmt.Errorf("failed to parse: %w", err)}
+	}
+	if file.Version != 1 {
+		// Written by a newer quic, so not overwritten
+		return nil, fmt.Errorf("unsupported version %d of credentials file %s", file.Version, s.path)
+	}
+
+	gcm, err := s.cipher(file.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := gcm.Open(nil, file.Nonce, file.Data, nil)
+	if err != nil {
+		return nil, &UnreadableError{Path: s.path, Err: fmt.Errorf("failed to decrypt (wrong %s?)", EnvCredentialsPassphrase)}
+	}
+
+	if err := json.Unmarshal(plaintext, &values); err != nil {
+		return nil, &UnreadableError{Path: s.path, Err: fmt.Errorf("failed to parse credentials: %w", err)}
+	}
+	return values, nil
+}
+
+// write encrypts values with a fresh nonce and atomically replaces the file
+func (s *FileStore) write(values map[string]string) error {
+	if s.salt == nil {
+		s.salt = make([]byte, 16)
+		if _, err := rand.Read(s.salt); err != nil {
+			return fmt.Errorf("failed to generate salt: %w", err)
+		}
+	}
+
+	gcm, err := s.cipher(s.salt)
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := json.Marshal(values)
+	if err != nil {
+		return fmt.Errorf("failed to marshal credentials: %w", err)
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	data, err := json.Marshal(encryptedFile{
+		Version: 1,
+		Salt:    s.salt,
+		Nonce:   nonce,
+		Data:    gcm.Seal(nil, nonce, plaintext, nil),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal credentials file: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), "."+filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	// CreateTemp already uses 0600, so the file is never readable by others
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write credentials file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write credentials file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to replace credentials file: %w", err)
+	}
+	return nil
+}
+
+// cipher returns an AES-GCM cipher keyed for the given salt
+func (s *FileStore) cipher(salt []byte) (cipher.AEAD, error) {
+	if s.key == nil || string(salt) != string(s.salt) {
+		key, err := pbkdf2.Key(sha256.New, s.passphrase, salt, pbkdf2Iterations, 32)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive key: %w", err)
+		}
+		s.key = key
+		s.salt = salt
+	}
+
+	block, err := aes.NewCipher(s.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// machinePassphrase derives a passphrase from identifiers of this machine and
+// user. Only identifiers that survive reboots and network changes are used;
+// the hostname, for one, changes with DHCP and container restarts.
+func machinePassphrase() string {
+	parts := []string{"quic-cli"}
+
+	for _, path := range []string{"/etc/machine-id", "/var/lib/dbus/machine-id"} {
+		if id, err := os.ReadFile(path); err == nil {
+			parts = append(parts, strings.TrimSpace(string(id)))
+			break
+		}
+	}
+
+	parts = append(parts, fmt.Sprint(os.Getuid()))
+
+	return strings.Join(parts, "\x00")
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestFileStore(t *testing.T, dir, passphrase string) *FileStore {
+	t.Helper()
+	return &FileStore{path: filepath.Join(dir, "credentials.enc"), passphrase: passphrase}
+}
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+
+	if err := newTestFileStore(t, dir, "one").Set("token", "secret"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	// A new process derives the key again from the stored salt
+	value, err := newTestFileStore(t, dir, "one").Get("token")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if value != "secret" {
+		t.Errorf("Get() = %q, want %q", value, "secret")
+	}
+}
+
+func TestFileStoreWrongPassphrase(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+
+	if err := newTestFileStore(t, dir, "one").Set("token", "secret"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	_, err := newTestFileStore(t, dir, "two").Get("token")
+	var unreadable *UnreadableError
+	if !errors.As(err, &unreadable) {
+		t.Fatalf("Get() error = %v, want UnreadableError", err)
+	}
+	if unreadable.Path != filepath.Join(dir, "credentials.enc") {
+		t.Errorf("UnreadableError.Path = %q", unreadable.Path)
+	}
+}
+
+func TestFileStoreSetReplacesUnreadableFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+
+	if err := newTestFileStore(t, dir, "one").Set("token", "old"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	store := newTestFileStore(t, dir, "two")
+	if err := store.Set("token", "new"); err != nil {
+		t.Fatalf("Set() over unreadable file error = %v", err)
+	}
+	value, err := newTestFileStore(t, dir, "two").Get("token")
+	if err != nil || value != "new" {
+		t.Errorf("Get() = %q, %v, want %q", value, err, "new")
+	}
+}
+
+func TestFileStoreDeleteRemovesUnreadableFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+
+	if err := newTestFileStore(t, dir, "one").Set("token", "secret"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	store := newTestFileStore(t, dir, "two")
+	if err := store.Delete("token"); err != nil {
+		t.Fatalf("Delete() of unreadable file error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "credentials.enc")); !os.IsNotExist(err) {
+		t.Errorf("credentials file still exists: %v", err)
+	}
+	if err := store.Delete("token"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Delete() error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,200 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/quicdb/quic-cli/internal/retry"
+	"github.com/quicdb/quic-cli/internal/userconfig"
+)
+
+// jwksTTL is how long a fetched key set is trusted before it is fetched
+// again. Keys missing from a cached set are always refetched, so rotation
+// does not have to wait for the TTL.
+const jwksTTL = 24 * time.Hour
+
+// minRSABits rejects keys too small to be trusted
+const minRSABits = 2048
+
+// ErrJWKSUnavailable is returned when the key set cannot be fetched. Unlike
+// ErrInvalidToken it says nothing about the token, so the operation can be
+// retried later.
+var ErrJWKSUnavailable = errors.New("signing keys unavailable")
+
+// jwk is a single JSON Web Key (RFC 7517). Only RSA keys are supported.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// jwksCache is the on-disk cache of a key set, stored as jwks.json under the
+// config directory
+type jwksCache struct {
+	URL       string    `json:"url"`
+	FetchedAt time.Time `json:"fetchedAt"`
+	Keys      []jwk     `json:"keys"`
+}
+
+var (
+	jwksMu     sync.Mutex
+	jwksMemory *jwksCache
+)
+
+// publicKey returns the RSA key with the given key ID from the JWKS at url,
+// using the cached key set when it is fresh and contains the key
+func publicKey(url, kid string) (*rsa.PublicKey, error) {
+	jwksMu.Lock()
+	defer jwksMu.Unlock()
+
+	cache := jwksMemory
+	if cache == nil || cache.URL != url {
+		cache = readJWKSCache(url)
+	}
+
+	if cache != nil && time.Since(cache.FetchedAt) < jwksTTL {
+		if key := findKey(cache.Keys, kid); key != nil {
+			jwksMemory = cache
+			return parseRSAKey(key)
+		}
+	}
+
+	keys, err := fetchJWKS(url)
+	if err != nil {
+		return nil, err
+	}
+
+	cache = &jwksCache{URL: url, FetchedAt: time.Now(), Keys: keys}
+	jwksMemory = cache
+	writeJWKSCache(cache)
+
+	key := findKey(keys, kid)
+	if key == nil {
+		return nil, fmt.Errorf("%w: signing key %q not found in %s", ErrInvalidToken, kid, url)
+	}
+	return parseRSAKey(key)
+}
+
+func findKey(keys []jwk, kid string) *jwk {
+	for i := range keys {
+		if keys[i].Kid == kid {
+			return &keys[i]
+		}
+	}
+	return nil
+}
+
+// fetchJWKS downloads the key set at url
+func fetchJWKS(url string) ([]jwk, error) {
+	client := retry.NewClient(httpAttemptTimeout)
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("%w: error fetching JWKS: %w", ErrJWKSUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: error reading JWKS: %w", ErrJWKSUnavailable, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: error fetching JWKS: HTTP %d - %s", ErrJWKSUnavailable, resp.StatusCode, string(body))
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(body, &set); err != nil {
+		return nil, fmt.Errorf("%w: error parsing JWKS: %w", ErrJWKSUnavailable, err)
+	}
+	return set.Keys, nil
+}
+
+// parseRSAKey converts a JWK into an RSA public key
+func parseRSAKey(key *jwk) (*rsa.PublicKey, error) {
+	if key.Kty != "RSA" {
+		return nil, fmt.Errorf("%w: unsupported key type %q", ErrInvalidToken, key.Kty)
+	}
+	if key.Use != "" && key.Use != "sig" {
+		return nil, fmt.Errorf("%w: key %q is not a signing key", ErrInvalidToken, key.Kid)
+	}
+
+	n, err := base64.RawURLEncoding.DecodeString(key.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus in key %q: %w", key.Kid, err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(key.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent in key %q: %w", key.Kid, err)
+	}
+
+	pub := &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}
+	if pub.N.BitLen() < minRSABits {
+		return nil, fmt.Errorf("%w: key %q is only %d bits", ErrInvalidToken, key.Kid, pub.N.BitLen())
+	}
+	return pub, nil
+}
+
+func jwksCachePath() (string, error) {
+	dir, err := userconfig.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "jwks.json"), nil
+}
+
+// readJWKSCache returns the cached key set for url, or nil if there is none.
+// The cache only holds public keys, so any problem reading it just means
+// fetching again.
+func readJWKSCache(url string) *jwksCache {
+	path, err := jwksCachePath()
+	if err != nil {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var cache jwksCache
+	if err := json.Unmarshal(data, &cache); err != nil || cache.URL != url {
+		return nil
+	}
+	return &cache
+}
+
+// writeJWKSCache stores the key set on disk, best effort
+func writeJWKSCache(cache *jwksCache) {
+	path, err := jwksCachePath()
+	if err != nil {
+		return
+	}
+
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/quicdb/quic-cli/internal/retry"
+	"github.com/quicdb/quic-cli/internal/userconfig"
+)
+
+// lockTimeout bounds how long a process waits for another one to release the
+// credentials lock, which is only held while the file is rewritten
+const lockTimeout = 30 * time.Second
+
+// refreshLockTimeout bounds how long a process waits for another one to finish
+// refreshing. The holder may retry both the token request and the JWKS fetch
+// up to the retry policy's limits, so waiting less would give up on a refresh
+// that is still in progress.
+func refreshLockTimeout() time.Duration {
+	return 2*retry.CurrentPolicy().MaxDuration(httpAttemptTimeout) + lockTimeout
+}
+
+// lockRefresh takes an exclusive file lock under the config directory so that
+// only one quic process exchanges the refresh token at a time. The returned
+// function releases the lock.
+func lockRefresh() (func(), error) {
+	// Profiles hold independent refresh tokens, so each gets its own lock
+	name := "refresh.lock"
+	if profile := userconfig.Profile(); profile != userconfig.DefaultProfile {
+		name = "refresh-" + profile + ".lock"
+	}
+	return lockFile(name, refreshLockTimeout())
+}
+
+// lockFile takes an exclusive lock on the named file under the config
+// directory, waiting up to timeout for other processes to release it.
+// The returned function releases the lock.
+func lockFile(name string, timeout time.Duration) (func(), error) {
+	dir, err := userconfig.Dir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, name), os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		locked, err := tryLockFile(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", f.Name(), err)
+		}
+		if locked {
+			break
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("timed out waiting for %s", f.Name())
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
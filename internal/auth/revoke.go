@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/quicdb/quic-cli/internal/config"
+	"github.com/quicdb/quic-cli/internal/retry"
+)
+
+// Token type hints for RevokeToken (RFC 7009)
+const (
+	HintAccessToken  = "access_token"
+	HintRefreshToken = "refresh_token"
+)
+
+// RevokeToken invalidates a token at the identity provider's revocation
+// endpoint. clientSecret is only needed for tokens issued to M2M clients.
+// Revoking a token that is already invalid succeeds.
+func RevokeToken(token, hint, clientID, clientSecret string) error {
+	cfg := config.Get()
+	url := fmt.Sprintf("%s/v1/public/%s/oauth2/revoke", cfg.StytchURL, cfg.ProjectID)
+
+	// Prepare the request body
+	body := map[string]string{
+		"client_id":       clientID,
+		"token":           token,
+		"token_type_hint": hint,
+	}
+	if clientSecret != "" {
+		body["client_secret"] = clientSecret
+	}
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("error marshaling request body: %w", err)
+	}
+
+	// Create the request; revoking is idempotent (RFC 7009)
+	req, err := http.NewRequestWithContext(retry.Idempotent(context.Background()), "POST", url, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+
+	// Set headers
+	req.Header.Set("Content-Type", "application/json")
+
+	// Send the request
+	client := retry.NewClient(httpAttemptTimeout)
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error making revocation request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("revocation error: HTTP %d - %s", resp.StatusCode, string(respBody))
+	}
+
+	return nil
+}
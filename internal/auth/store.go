@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/quicdb/quic-cli/internal/userconfig"
+	"github.com/zalando/go-keyring"
+)
+
+// ErrNotFound is returned by a Store when no value exists for a key
+var ErrNotFound = errors.New("credential not found")
+
+// Store persists credentials by key
+type Store interface {
+	Get(key string) (string, error)
+	Set(key, value string) error
+	Delete(key string) error
+}
+
+// Credential store backends selectable via config or QUIC_CREDENTIAL_STORE
+const (
+	StoreAuto    = "auto"
+	StoreKeyring = "keyring"
+	StoreFile    = "file"
+)
+
+// EnvCredentialStore overrides the configured credential store backend
+const EnvCredentialStore = "QUIC_CREDENTIAL_STORE"
+
+var (
+	storeMu     sync.Mutex
+	activeStore Store
+)
+
+// SetStore replaces the credential store, e.g. with a MemoryStore in tests
+func SetStore(s Store) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	activeStore = s
+}
+
+// currentStore returns the active store, selecting it on first use
+func currentStore() (Store, error) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	if activeStore != nil {
+		return activeStore, nil
+	}
+
+	s, err := selectStore()
+	if err != nil {
+		return nil, err
+	}
+	activeStore = s
+	return s, nil
+}
+
+// selectStore picks the backend from QUIC_CREDENTIAL_STORE or the user
+// config. In auto mode the OS keyring is used when it is reachable, with the
+// encrypted file as fallback for hosts without a Secret Service.
+func selectStore() (Store, error) {
+	backend := os.Getenv(EnvCredentialStore)
+	if backend == "" {
+		settings, err := userconfig.LoadSettings()
+		if err != nil {
+			return nil, err
+		}
+		backend = settings.CredentialStore
+	}
+
+	switch backend {
+	case StoreKeyring:
+		return &KeyringStore{Service: service}, nil
+	case StoreFile:
+		return NewFileStore()
+	case "", StoreAuto:
+		if keyringAvailable() {
+			return &KeyringStore{Service: service}, nil
+		}
+		return NewFileStore()
+	default:
+		return nil, fmt.Errorf("unknown credential store %q (expected auto, keyring or file)", backend)
+	}
+}
+
+// keyringAvailable probes the OS keyring with a lookup that is expected to miss
+func keyringAvailable() bool {
+	_, err := keyring.Get(service, "probe")
+	return err == nil || errors.Is(err, keyring.ErrNotFound)
+}
+
+// KeyringStore keeps credentials in the OS keychain/credential manager
+type KeyringStore struct {
+	Service string
+}
+
+func (s *KeyringStore) Get(key string) (string, error) {
+	value, err := keyring.Get(s.Service, key)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrNotFound
+	}
+	return value, err
+}
+
+func (s *KeyringStore) Set(key, value string) error {
+	return keyring.Set(s.Service, key, value)
+}
+
+func (s *KeyringStore) Delete(key string) error {
+	err := keyring.Delete(s.Service, key)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// MemoryStore keeps credentials in process memory only
+type MemoryStore struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+// NewMemoryStore returns an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{values: make(map[string]string)}
+}
+
+func (s *MemoryStore) Get(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, ok := s.values[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+func (s *MemoryStore) Set(key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values[key] = value
+	return nil
+}
+
+func (s *MemoryStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.values[key]; !ok {
+		return ErrNotFound
+	}
+	delete(s.values, key)
+	return nil
+}
@@ -2,14 +2,18 @@ package auth
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/quicdb/quic-cli/internal/config"
-	"github.com/zalando/go-keyring"
+	"github.com/quicdb/quic-cli/internal/retry"
+	"github.com/quicdb/quic-cli/internal/userconfig"
 )
 
 const (
@@ -19,48 +23,182 @@ const (
 
 type TokenType string
 
+// ErrNotLoggedIn is returned when no usable credentials are stored
+var ErrNotLoggedIn = errors.New("you are not logged in. Please run 'quic login' first")
+
+const (
+	AccessToken       TokenType = "access_token"
+	AccessTokenExpiry TokenType = "access_token_expiry"
+	RefreshToken      TokenType = "refresh_token"
+	M2MClientID       TokenType = "m2m_client_id"
+	M2MClientSecret   TokenType = "m2m_client_secret"
+	LoginFlow         TokenType = "login_flow"
+)
+
+// Login flows as reported by LoadLoginFlow
 const (
-	AccessToken     TokenType = "access_token"
-	RefreshToken    TokenType = "refresh_token"
-	M2MClientID     TokenType = "m2m_client_id"
-	M2MClientSecret TokenType = "m2m_client_secret"
+	FlowPKCE   = "pkce"
+	FlowDevice = "device"
+	FlowM2M    = "m2m"
 )
 
-// SaveToken persists the token securely in the OS keychain/credential manager
+// refreshSkew is how long before expiry an access token is proactively
+// refreshed, leaving room for clock drift and request latency
+const refreshSkew = 60 * time.Second
+
+// httpAttemptTimeout bounds each attempt of a request to the identity provider
+const httpAttemptTimeout = 30 * time.Second
+
+// tokenKey namespaces a token by profile. The default profile keeps the
+// un-namespaced keys so credentials from before profiles remain valid.
+func tokenKey(profile string, tokenType TokenType) string {
+	if profile == userconfig.DefaultProfile {
+		return user + string(tokenType)
+	}
+	return user + "@" + profile + "/" + string(tokenType)
+}
+
+// SaveToken persists the token for the active profile in the credential
+// store (OS keychain by default)
 func SaveToken(token string, tokenType TokenType) error {
-	return keyring.Set(service, user+string(tokenType), token)
+	store, err := currentStore()
+	if err != nil {
+		return err
+	}
+	return store.Set(tokenKey(userconfig.Profile(), tokenType), token)
 }
 
-// LoadToken retrieves the token from the OS keychain/credential manager
+// LoadToken retrieves the token for the active profile from the credential store
 func LoadToken(tokenType TokenType) (string, error) {
-	return keyring.Get(service, user+string(tokenType))
+	return LoadProfileToken(userconfig.Profile(), tokenType)
 }
 
-// DeleteToken removes the token from the OS keychain/credential manager
+// LoadProfileToken retrieves the token for the given profile from the credential store
+func LoadProfileToken(profile string, tokenType TokenType) (string, error) {
+	store, err := currentStore()
+	if err != nil {
+		return "", err
+	}
+	return store.Get(tokenKey(profile, tokenType))
+}
+
+// DeleteToken removes the token for the active profile from the credential store
 func DeleteToken(tokenType TokenType) error {
-	return keyring.Delete(service, user+string(tokenType))
+	return deleteProfileToken(userconfig.Profile(), tokenType)
 }
 
-// ClearAllTokens removes all stored tokens (useful for logout)
-func ClearAllTokens() error {
-	// Try to delete all tokens and credentials, return the last error if any
-	var lastErr error
+func deleteProfileToken(profile string, tokenType TokenType) error {
+	store, err := currentStore()
+	if err != nil {
+		return err
+	}
+	return store.Delete(tokenKey(profile, tokenType))
+}
 
-	if err := DeleteToken(AccessToken); err != nil && !errors.Is(err, keyring.ErrNotFound) {
-		lastErr = err
+// SaveAccessToken stores an access token along with its expiry, computed from
+// the expires_in value of the token response. A non-positive expiresIn means
+// the expiry is unknown.
+func SaveAccessToken(token string, expiresIn int) error {
+	if err := SaveToken(token, AccessToken); err != nil {
+		return err
 	}
 
-	if err := DeleteToken(RefreshToken); err != nil && !errors.Is(err, keyring.ErrNotFound) {
-		lastErr = err
+	if expiresIn <= 0 {
+		if err := DeleteToken(AccessTokenExpiry); err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		return nil
 	}
 
-	if err := ClearM2MCredentials(); err != nil && !errors.Is(err, keyring.ErrNotFound) {
-		lastErr = err
+	expiry := time.Now().Add(time.Duration(expiresIn) * time.Second)
+	return SaveToken(strconv.FormatInt(expiry.Unix(), 10), AccessTokenExpiry)
+}
+
+// LoadAccessTokenExpiry returns when the stored access token expires. It falls
+// back to the token's exp claim for tokens saved without an expiry.
+func LoadAccessTokenExpiry() (time.Time, error) {
+	if stored, err := LoadToken(AccessTokenExpiry); err == nil {
+		if unix, err := strconv.ParseInt(stored, 10, 64); err == nil {
+			return time.Unix(unix, 0), nil
+		}
+	}
+
+	token, err := LoadToken(AccessToken)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	claims, err := ParseClaims(token)
+	if err != nil || claims.ExpiresAt == 0 {
+		return time.Time{}, fmt.Errorf("access token expiry unknown")
+	}
+	return time.Unix(claims.ExpiresAt, 0), nil
+}
+
+// ValidAccessToken returns the stored access token, refreshing it first when
+// it expires within refreshSkew. If the refresh fails the current token is
+// returned so the caller can still fall back to refreshing after a 401.
+func ValidAccessToken() (string, error) {
+	token, err := LoadToken(AccessToken)
+	if errors.Is(err, ErrNotFound) {
+		return "", ErrNotLoggedIn
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read credentials: %w", err)
+	}
+
+	if !expiresSoon() {
+		return token, nil
+	}
+
+	if err := RefreshAccessToken(token); err != nil {
+		return token, nil
+	}
+
+	refreshed, err := LoadToken(AccessToken)
+	if err != nil {
+		return token, nil
+	}
+	return refreshed, nil
+}
+
+// expiresSoon reports whether the stored access token expires within refreshSkew
+func expiresSoon() bool {
+	expiry, err := LoadAccessTokenExpiry()
+	return err == nil && time.Until(expiry) <= refreshSkew
+}
+
+// ClearAllTokens removes all stored tokens of the active profile (useful for logout)
+func ClearAllTokens() error {
+	return ClearProfileTokens(userconfig.Profile())
+}
+
+// ClearProfileTokens removes all stored tokens of the given profile
+func ClearProfileTokens(profile string) error {
+	// Try to delete all tokens and credentials, return the last error if any
+	var lastErr error
+
+	for _, tokenType := range []TokenType{AccessToken, AccessTokenExpiry, RefreshToken, M2MClientID, M2MClientSecret, LoginFlow} {
+		if err := deleteProfileToken(profile, tokenType); err != nil && !errors.Is(err, ErrNotFound) {
+			lastErr = err
+		}
 	}
 
 	return lastErr
 }
 
+// LoadLoginFlow returns how the active profile logged in. Logins from before
+// the flow was recorded are inferred from the stored credentials.
+func LoadLoginFlow() string {
+	if flow, err := LoadToken(LoginFlow); err == nil {
+		return flow
+	}
+	if _, err := LoadToken(M2MClientID); err == nil {
+		return FlowM2M
+	}
+	return FlowPKCE
+}
+
 // SaveM2MCredentials stores M2M client credentials securely
 func SaveM2MCredentials(clientID, clientSecret string) error {
 	if err := SaveToken(clientID, M2MClientID); err != nil {
@@ -72,15 +210,15 @@ func SaveM2MCredentials(clientID, clientSecret string) error {
 	return nil
 }
 
-// ClearM2MCredentials removes M2M credentials from keyring
+// ClearM2MCredentials removes M2M credentials from the credential store
 func ClearM2MCredentials() error {
 	var lastErr error
 
-	if err := DeleteToken(M2MClientID); err != nil && !errors.Is(err, keyring.ErrNotFound) {
+	if err := DeleteToken(M2MClientID); err != nil && !errors.Is(err, ErrNotFound) {
 		lastErr = err
 	}
 
-	if err := DeleteToken(M2MClientSecret); err != nil && !errors.Is(err, keyring.ErrNotFound) {
+	if err := DeleteToken(M2MClientSecret); err != nil && !errors.Is(err, ErrNotFound) {
 		lastErr = err
 	}
 
@@ -97,8 +235,27 @@ type RefreshTokenResponse struct {
 	StatusCode   int    `json:"status_code"`
 }
 
-// RefreshAccessToken exchanges a refresh token OR M2M credentials for a new access token
-func RefreshAccessToken() error {
+// RefreshAccessToken exchanges a refresh token OR M2M credentials for a new
+// access token. staleToken is the access token the caller found unusable; the
+// exchange is skipped if another process already replaced it. Refreshes are
+// serialized across processes because refresh tokens rotate on every use.
+func RefreshAccessToken(staleToken string) error {
+	unlock, err := lockRefresh()
+	if err != nil {
+		// Refreshing without the lock could spend a refresh token another
+		// process is still using, so only accept a refresh it completed
+		if refreshedElsewhere(staleToken) {
+			return nil
+		}
+		return fmt.Errorf("failed to refresh access token: %w", err)
+	}
+	defer unlock()
+
+	// Re-read the credential store now that we hold the lock
+	if refreshedElsewhere(staleToken) {
+		return nil
+	}
+
 	// Try refresh token flow first (OAuth/PKCE)
 	refreshToken, err := LoadToken(RefreshToken)
 	if err == nil {
@@ -119,6 +276,13 @@ func RefreshAccessToken() error {
 	return refreshWithM2MCredentials(clientID, clientSecret)
 }
 
+// refreshedElsewhere reports whether the stored access token was replaced
+// since the caller found staleToken unusable and is not about to expire
+func refreshedElsewhere(staleToken string) bool {
+	current, err := LoadToken(AccessToken)
+	return err == nil && current != staleToken && !expiresSoon()
+}
+
 // refreshWithRefreshToken uses OAuth refresh token to get new access token
 func refreshWithRefreshToken(refreshToken string) error {
 	cfg := config.Get()
@@ -145,7 +309,7 @@ func refreshWithRefreshToken(refreshToken string) error {
 	req.Header.Set("Content-Type", "application/json")
 
 	// Send the request
-	client := &http.Client{}
+	client := retry.NewClient(httpAttemptTimeout)
 	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("error making refresh request: %w", err)
@@ -168,18 +332,29 @@ func refreshWithRefreshToken(refreshToken string) error {
 		return fmt.Errorf("refresh token error: %s", string(respBody))
 	}
 
-	// Save the new tokens
-	if err := SaveToken(tokenResp.AccessToken, AccessToken); err != nil {
-		return fmt.Errorf("failed to save new access token: %w", err)
-	}
-
-	// Update refresh token if a new one was provided
+	// Update refresh token if a new one was provided. This happens before
+	// verifying the access token: refresh tokens rotate, so the old one is
+	// already spent and dropping the new one would end the session.
 	if tokenResp.RefreshToken != "" {
 		if err := SaveToken(tokenResp.RefreshToken, RefreshToken); err != nil {
 			return fmt.Errorf("failed to save new refresh token: %w", err)
 		}
 	}
 
+	// An unverified access token is not stored. If only the JWKS could not be
+	// fetched, the next request refreshes again with the saved refresh token.
+	if _, err := VerifyToken(tokenResp.AccessToken); err != nil {
+		if errors.Is(err, ErrJWKSUnavailable) {
+			return fmt.Errorf("could not verify refreshed access token, try again: %w", err)
+		}
+		return fmt.Errorf("rejected refreshed access token: %w", err)
+	}
+
+	// Save the new access token
+	if err := SaveAccessToken(tokenResp.AccessToken, tokenResp.ExpiresIn); err != nil {
+		return fmt.Errorf("failed to save new access token: %w", err)
+	}
+
 	return nil
 }
 
@@ -199,8 +374,8 @@ func refreshWithM2MCredentials(clientID, clientSecret string) error {
 		return fmt.Errorf("error marshaling request body: %w", err)
 	}
 
-	// Create the request
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
+	// Create the request; the client credentials grant is safe to repeat
+	req, err := http.NewRequestWithContext(retry.Idempotent(context.Background()), "POST", url, bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return fmt.Errorf("error creating request: %w", err)
 	}
@@ -209,7 +384,7 @@ func refreshWithM2MCredentials(clientID, clientSecret string) error {
 	req.Header.Set("Content-Type", "application/json")
 
 	// Send the request
-	client := &http.Client{}
+	client := retry.NewClient(httpAttemptTimeout)
 	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("error making M2M token request: %w", err)
@@ -233,7 +408,7 @@ func refreshWithM2MCredentials(clientID, clientSecret string) error {
 	}
 
 	// Save the new access token
-	if err := SaveToken(tokenResp.AccessToken, AccessToken); err != nil {
+	if err := SaveAccessToken(tokenResp.AccessToken, tokenResp.ExpiresIn); err != nil {
 		return fmt.Errorf("failed to save new access token: %w", err)
 	}
 
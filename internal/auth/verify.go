@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/quicdb/quic-cli/internal/config"
+)
+
+// ErrInvalidToken is returned when a token fails verification
+var ErrInvalidToken = errors.New("invalid token")
+
+// clockSkew is the leeway allowed when checking exp and nbf
+const clockSkew = 60 * time.Second
+
+// jwtHeader is the JOSE header of a signed JWT
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// signingHashes are the supported RSA signature algorithms
+var signingHashes = map[string]crypto.Hash{
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+}
+
+// VerifyToken checks a JWT issued to the CLI: its signature against the
+// project's JWKS, its issuer, that config.ClientID is in its audience, and
+// that it is within its validity period. It returns the verified claims.
+func VerifyToken(token string) (*Claims, error) {
+	cfg := config.Get()
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: not a JWT", ErrInvalidToken)
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to decode header: %v", ErrInvalidToken, err)
+	}
+	var header jwtHeader
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, fmt.Errorf("%w: failed to parse header: %v", ErrInvalidToken, err)
+	}
+
+	// The algorithm is pinned to RSA so a token cannot downgrade to "none"
+	// or an HMAC keyed with the public key
+	hash, ok := signingHashes[header.Alg]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported signing algorithm %q", ErrInvalidToken, header.Alg)
+	}
+
+	key, err := publicKey(cfg.JWKSURL, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to decode signature: %v", ErrInvalidToken, err)
+	}
+
+	h := hash.New()
+	h.Write([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, hash, h.Sum(nil), signature); err != nil {
+		return nil, fmt.Errorf("%w: signature verification failed", ErrInvalidToken)
+	}
+
+	claims, err := ParseClaims(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if claims.Issuer != cfg.Issuer {
+		return nil, fmt.Errorf("%w: issuer %q does not match %q", ErrInvalidToken, claims.Issuer, cfg.Issuer)
+	}
+	if !slices.Contains(claims.Audience, cfg.ClientID) {
+		return nil, fmt.Errorf("%w: audience %v does not include %q", ErrInvalidToken, []string(claims.Audience), cfg.ClientID)
+	}
+
+	now := time.Now()
+	if claims.ExpiresAt == 0 || now.After(time.Unix(claims.ExpiresAt, 0).Add(clockSkew)) {
+		return nil, fmt.Errorf("%w: token has expired", ErrInvalidToken)
+	}
+	if claims.NotBefore != 0 && now.Before(time.Unix(claims.NotBefore, 0).Add(-clockSkew)) {
+		return nil, fmt.Errorf("%w: token is not valid yet", ErrInvalidToken)
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,339 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/quicdb/quic-cli/internal/config"
+	"github.com/quicdb/quic-cli/internal/retry"
+)
+
+const (
+	testClientID = "project-test-client"
+	testIssuer   = "stytch.com/project-test"
+)
+
+// Generating RSA keys is slow, so the tests share two
+var (
+	testKeysOnce sync.Once
+	testKey      *rsa.PrivateKey
+	otherKey     *rsa.PrivateKey
+)
+
+func testKeys(t *testing.T) (*rsa.PrivateKey, *rsa.PrivateKey) {
+	t.Helper()
+	testKeysOnce.Do(func() {
+		var err error
+		if testKey, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+			panic(err)
+		}
+		if otherKey, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+			panic(err)
+		}
+	})
+	return testKey, otherKey
+}
+
+// jwksServer serves the public keys it holds as a JWKS and counts fetches
+type jwksServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	keys    map[string]*rsa.PublicKey
+	fetches atomic.Int32
+}
+
+func (s *jwksServer) setKey(kid string, key *rsa.PublicKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[kid] = key
+}
+
+// setupVerify points the configuration at a fresh JWKS server, isolates the
+// on-disk cache in a temporary home directory and clears the in-memory cache
+func setupVerify(t *testing.T) *jwksServer {
+	t.Helper()
+
+	s := &jwksServer{keys: map[string]*rsa.PublicKey{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.fetches.Add(1)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		var set struct {
+			Keys []jwk `json:"keys"`
+		}
+		for kid, key := range s.keys {
+			set.Keys = append(set.Keys, jwk{
+				Kty: "RSA",
+				Kid: kid,
+				Use: "sig",
+				Alg: "RS256",
+				N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		json.NewEncoder(w).Encode(set)
+	}))
+	t.Cleanup(s.Close)
+
+	t.Setenv("HOME", t.TempDir())
+	setConfig(t, &config.JWKSURL, s.URL+"/jwks")
+	setConfig(t, &config.Issuer, testIssuer)
+	setConfig(t, &config.ClientID, testClientID)
+
+	jwksMu.Lock()
+	jwksMemory = nil
+	jwksMu.Unlock()
+
+	return s
+}
+
+func setConfig(t *testing.T, v *string, value string) {
+	t.Helper()
+	old := *v
+	*v = value
+	t.Cleanup(func() { *v = old })
+}
+
+// validClaims returns claims that pass verification
+func validClaims() map[string]any {
+	now := time.Now()
+	return map[string]any{
+		"sub": "user-test",
+		"iss": testIssuer,
+		"aud": []string{testClientID},
+		"exp": now.Add(time.Hour).Unix(),
+		"nbf": now.Add(-time.Minute).Unix(),
+	}
+}
+
+func encodeSegment(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// signRS256 returns a JWT with the given claims signed by key
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]any) string {
+	t.Helper()
+	signed := encodeSegment(t, jwtHeader{Alg: "RS256", Kid: kid}) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func expectInvalid(t *testing.T, token, reason string) {
+	t.Helper()
+	_, err := VerifyToken(token)
+	if !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("VerifyToken() error = %v, want ErrInvalidToken", err)
+	}
+	if !strings.Contains(err.Error(), reason) {
+		t.Errorf("VerifyToken() error = %q, want it to mention %q", err, reason)
+	}
+}
+
+func TestVerifyTokenValid(t *testing.T) {
+	key, _ := testKeys(t)
+	s := setupVerify(t)
+	s.setKey("key-1", &key.PublicKey)
+
+	claims, err := VerifyToken(signRS256(t, key, "key-1", validClaims()))
+	if err != nil {
+		t.Fatalf("VerifyToken() error = %v", err)
+	}
+	if claims.Subject != "user-test" {
+		t.Errorf("Subject = %q, want %q", claims.Subject, "user-test")
+	}
+}
+
+func TestVerifyTokenBadSignature(t *testing.T) {
+	key, other := testKeys(t)
+	s := setupVerify(t)
+	s.setKey("key-1", &key.PublicKey)
+
+	expectInvalid(t, signRS256(t, other, "key-1", validClaims()), "signature verification failed")
+}
+
+func TestVerifyTokenRejectsAlgorithms(t *testing.T) {
+	key, _ := testKeys(t)
+	s := setupVerify(t)
+	s.setKey("key-1", &key.PublicKey)
+
+	payload := encodeSegment(t, validClaims())
+
+	t.Run("none", func(t *testing.T) {
+		token := encodeSegment(t, jwtHeader{Alg: "none", Kid: "key-1"}) + "." + payload + "."
+		expectInvalid(t, token, "unsupported signing algorithm")
+	})
+
+	t.Run("HS256", func(t *testing.T) {
+		// An HMAC keyed with the public key, as in the classic key confusion attack
+		signed := encodeSegment(t, jwtHeader{Alg: "HS256", Kid: "key-1"}) + "." + payload
+		mac := hmac.New(sha256.New, key.PublicKey.N.Bytes())
+		mac.Write([]byte(signed))
+		token := signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+		expectInvalid(t, token, "unsupported signing algorithm")
+	})
+}
+
+func TestVerifyTokenClaims(t *testing.T) {
+	key, _ := testKeys(t)
+	s := setupVerify(t)
+	s.setKey("key-1", &key.PublicKey)
+
+	tests := []struct {
+		name   string
+		modify func(claims map[string]any)
+		reason string
+	}{
+		{
+			name:   "wrong issuer",
+			modify: func(c map[string]any) { c["iss"] = "stytch.com/project-other" },
+			reason: "issuer",
+		},
+		{
+			name:   "audience without client ID",
+			modify: func(c map[string]any) { c["aud"] = []string{"project-other-client"} },
+			reason: "audience",
+		},
+		{
+			name:   "missing audience",
+			modify: func(c map[string]any) { delete(c, "aud") },
+			reason: "audience",
+		},
+		{
+			name:   "expired",
+			modify: func(c map[string]any) { c["exp"] = time.Now().Add(-2 * clockSkew).Unix() },
+			reason: "expired",
+		},
+		{
+			name:   "missing expiry",
+			modify: func(c map[string]any) { delete(c, "exp") },
+			reason: "expired",
+		},
+		{
+			name:   "not valid yet",
+			modify: func(c map[string]any) { c["nbf"] = time.Now().Add(2 * clockSkew).Unix() },
+			reason: "not valid yet",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := validClaims()
+			tt.modify(claims)
+			expectInvalid(t, signRS256(t, key, "key-1", claims), tt.reason)
+		})
+	}
+}
+
+func TestVerifyTokenAudienceString(t *testing.T) {
+	key, _ := testKeys(t)
+	s := setupVerify(t)
+	s.setKey("key-1", &key.PublicKey)
+
+	claims := validClaims()
+	claims["aud"] = testClientID
+	if _, err := VerifyToken(signRS256(t, key, "key-1", claims)); err != nil {
+		t.Fatalf("VerifyToken() error = %v", err)
+	}
+}
+
+func TestVerifyTokenClockSkew(t *testing.T) {
+	key, _ := testKeys(t)
+	s := setupVerify(t)
+	s.setKey("key-1", &key.PublicKey)
+
+	claims := validClaims()
+	claims["exp"] = time.Now().Add(-clockSkew / 2).Unix()
+	claims["nbf"] = time.Now().Add(clockSkew / 2).Unix()
+	if _, err := VerifyToken(signRS256(t, key, "key-1", claims)); err != nil {
+		t.Fatalf("VerifyToken() error = %v", err)
+	}
+}
+
+func TestVerifyTokenUnknownKeyRefetches(t *testing.T) {
+	key, other := testKeys(t)
+	s := setupVerify(t)
+	s.setKey("key-1", &key.PublicKey)
+
+	if _, err := VerifyToken(signRS256(t, key, "key-1", validClaims())); err != nil {
+		t.Fatalf("VerifyToken() error = %v", err)
+	}
+
+	// The provider rotates in a new key while the cache is still fresh
+	s.setKey("key-2", &other.PublicKey)
+	if _, err := VerifyToken(signRS256(t, other, "key-2", validClaims())); err != nil {
+		t.Fatalf("VerifyToken() with rotated key error = %v", err)
+	}
+	if got := s.fetches.Load(); got != 2 {
+		t.Errorf("JWKS fetched %d times, want 2", got)
+	}
+
+	// A key the provider does not have is refetched but still rejected
+	expectInvalid(t, signRS256(t, other, "key-3", validClaims()), "not found")
+	if got := s.fetches.Load(); got != 3 {
+		t.Errorf("JWKS fetched %d times, want 3", got)
+	}
+}
+
+func TestVerifyTokenUsesDiskCache(t *testing.T) {
+	key, _ := testKeys(t)
+	s := setupVerify(t)
+	s.setKey("key-1", &key.PublicKey)
+
+	token := signRS256(t, key, "key-1", validClaims())
+	if _, err := VerifyToken(token); err != nil {
+		t.Fatalf("VerifyToken() error = %v", err)
+	}
+
+	// A new process only has jwks.json
+	jwksMu.Lock()
+	jwksMemory = nil
+	jwksMu.Unlock()
+
+	if _, err := VerifyToken(token); err != nil {
+		t.Fatalf("VerifyToken() from disk cache error = %v", err)
+	}
+	if got := s.fetches.Load(); got != 1 {
+		t.Errorf("JWKS fetched %d times, want 1", got)
+	}
+}
+
+func TestVerifyTokenJWKSUnavailable(t *testing.T) {
+	key, _ := testKeys(t)
+	s := setupVerify(t)
+	s.Close()
+
+	retry.SetPolicy(retry.Policy{})
+	t.Cleanup(func() { retry.SetPolicy(retry.DefaultPolicy()) })
+
+	_, err := VerifyToken(signRS256(t, key, "key-1", validClaims()))
+	if !errors.Is(err, ErrJWKSUnavailable) {
+		t.Fatalf("VerifyToken() error = %v, want ErrJWKSUnavailable", err)
+	}
+	if errors.Is(err, ErrInvalidToken) {
+		t.Errorf("VerifyToken() error = %v, must not be ErrInvalidToken", err)
+	}
+}
@@ -0,0 +1,129 @@
+package cluster
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/quicdb/quic-cli/internal/api"
+)
+
+// NotFoundError is returned when a cluster reference matches no cluster
+type NotFoundError struct {
+	Ref         string
+	Suggestions []api.Cluster
+}
+
+func (e *NotFoundError) Error() string {
+	msg := fmt.Sprintf("cluster '%s' not found", e.Ref)
+	if len(e.Suggestions) > 0 {
+		msg += "\n\nDid you mean:\n" + describeClusters(e.Suggestions)
+	}
+	msg += "\n\nRun 'quic clusters ls' to see available clusters"
+	return msg
+}
+
+// AmbiguousError is returned when a cluster reference matches several clusters
+type AmbiguousError struct {
+	Ref        string
+	Candidates []api.Cluster
+}
+
+func (e *AmbiguousError) Error() string {
+	return fmt.Sprintf("cluster '%s' is ambiguous, it matches:\n%s\n\nUse the full cluster ID instead",
+		e.Ref, describeClusters(e.Candidates))
+}
+
+// minPrefixLength is the shortest ID prefix Match accepts, so that a short
+// name or typo cannot silently select a cluster whose ID happens to start
+// with it
+const minPrefixLength = 8
+
+// Match resolves a cluster reference against the available clusters. The
+// reference may be a cluster ID, name, subdomain or an unambiguous ID prefix
+// of at least minPrefixLength characters; earlier forms take precedence over
+// later ones.
+func Match(clusters []api.Cluster, ref string) (*api.Cluster, error) {
+	matchers := []func(api.Cluster) bool{
+		func(c api.Cluster) bool { return c.ID == ref },
+		func(c api.Cluster) bool { return c.Name == ref },
+		func(c api.Cluster) bool { return c.Subdomain != "" && c.Subdomain == ref },
+		func(c api.Cluster) bool { return len(ref) >= minPrefixLength && strings.HasPrefix(c.ID, ref) },
+	}
+
+	for _, matches := range matchers {
+		var found []api.Cluster
+		for _, c := range clusters {
+			if matches(c) {
+				found = append(found, c)
+			}
+		}
+
+		switch len(found) {
+		case 0:
+			continue
+		case 1:
+			return &found[0], nil
+		default:
+			return nil, &AmbiguousError{Ref: ref, Candidates: found}
+		}
+	}
+
+	return nil, &NotFoundError{Ref: ref, Suggestions: suggest(clusters, ref)}
+}
+
+// suggest returns clusters whose name or subdomain resembles ref, or whose ID
+// starts with a ref too short to be accepted as a prefix
+func suggest(clusters []api.Cluster, ref string) []api.Cluster {
+	ref = strings.ToLower(ref)
+
+	var suggestions []api.Cluster
+	for _, c := range clusters {
+		if ref != "" && strings.HasPrefix(strings.ToLower(c.ID), ref) {
+			suggestions = append(suggestions, c)
+			continue
+		}
+		for _, candidate := range []string{c.Name, c.Subdomain} {
+			candidate = strings.ToLower(candidate)
+			if candidate == "" {
+				continue
+			}
+			if strings.Contains(candidate, ref) || strings.Contains(ref, candidate) ||
+				levenshtein(candidate, ref) <= max(2, len(ref)/3) {
+				suggestions = append(suggestions, c)
+				break
+			}
+		}
+	}
+	return suggestions
+}
+
+// describeClusters renders clusters as an indented "name (id)" list
+func describeClusters(clusters []api.Cluster) string {
+	lines := make([]string, len(clusters))
+	for i, c := range clusters {
+		lines[i] = fmt.Sprintf("  %s (%s)", c.Name, c.ID)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// levenshtein returns the edit distance between a and b
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
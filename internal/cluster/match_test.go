@@ -0,0 +1,68 @@
+package cluster
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/quicdb/quic-cli/internal/api"
+)
+
+func TestMatch(t *testing.T) {
+	clusters := []api.Cluster{
+		{ID: "a1b2c3d4-0000-4000-8000-000000000001", Name: "production", Subdomain: "prod"},
+		{ID: "a1b2c3d4-0000-4000-8000-000000000002", Name: "staging", Subdomain: "stage"},
+		{ID: "f9e8d7c6-0000-4000-8000-000000000003", Name: "dev"},
+	}
+
+	tests := []struct {
+		ref     string
+		wantID  string
+		wantErr any
+	}{
+		{ref: "a1b2c3d4-0000-4000-8000-000000000001", wantID: clusters[0].ID},
+		{ref: "staging", wantID: clusters[1].ID},
+		{ref: "prod", wantID: clusters[0].ID},
+		{ref: "f9e8d7c6", wantID: clusters[2].ID},
+		{ref: "a1b2c3d4", wantErr: &AmbiguousError{}},
+		// Too short to be taken as an ID prefix
+		{ref: "f", wantErr: &NotFoundError{}},
+		{ref: "f9e8d7c", wantErr: &NotFoundError{}},
+		{ref: "qa", wantErr: &NotFoundError{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := Match(clusters, tt.ref)
+			switch want := tt.wantErr.(type) {
+			case *AmbiguousError:
+				if !errors.As(err, &want) {
+					t.Fatalf("Match() error = %v, want AmbiguousError", err)
+				}
+			case *NotFoundError:
+				if !errors.As(err, &want) {
+					t.Fatalf("Match() error = %v, want NotFoundError", err)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("Match() error = %v", err)
+				}
+				if got.ID != tt.wantID {
+					t.Errorf("Match() = %s, want %s", got.ID, tt.wantID)
+				}
+			}
+		})
+	}
+}
+
+func TestMatchSuggestsShortIDPrefix(t *testing.T) {
+	clusters := []api.Cluster{{ID: "f9e8d7c6-0000-4000-8000-000000000003", Name: "dev"}}
+
+	_, err := Match(clusters, "f9")
+	var notFound *NotFoundError
+	if !errors.As(err, &notFound) {
+		t.Fatalf("Match() error = %v, want NotFoundError", err)
+	}
+	if len(notFound.Suggestions) != 1 || notFound.Suggestions[0].ID != clusters[0].ID {
+		t.Errorf("Suggestions = %v, want the cluster whose ID starts with the reference", notFound.Suggestions)
+	}
+}
@@ -0,0 +1,58 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/quicdb/quic-cli/internal/api"
+	"github.com/quicdb/quic-cli/internal/prompt"
+	"github.com/quicdb/quic-cli/internal/userconfig"
+)
+
+// pickCluster lets the user choose a cluster interactively and offers to
+// remember the choice as the default cluster
+func pickCluster(clusters []api.Cluster) (string, error) {
+	options := make([]string, len(clusters))
+	for i, c := range clusters {
+		options[i] = describeCluster(c)
+	}
+
+	i, err := prompt.Select("Select a cluster", options)
+	if errors.Is(err, prompt.ErrCancelled) {
+		return "", fmt.Errorf("no cluster selected")
+	}
+	if err != nil {
+		return "", err
+	}
+	selected := clusters[i]
+
+	save, err := prompt.Confirm(fmt.Sprintf("Use %s as the default cluster from now on?", options[i]), false)
+	if err != nil {
+		return "", err
+	}
+	if save {
+		if err := userconfig.SetSelectedCluster(selected.ID); err != nil {
+			// The command can still proceed with the chosen cluster
+			fmt.Fprintf(os.Stderr, "Warning: Failed to save default cluster: %v\n", err)
+		}
+	}
+
+	return selected.ID, nil
+}
+
+// describeCluster renders a cluster as a single picker line
+func describeCluster(c api.Cluster) string {
+	name := c.Name
+	if name == "" {
+		name = c.ID
+	}
+	label := name
+	if c.Subdomain != "" {
+		label += " · " + c.Subdomain
+	}
+	if c.Region != "" {
+		label += " · " + c.Region
+	}
+	return fmt.Sprintf("%s (%s)", label, c.ID)
+}
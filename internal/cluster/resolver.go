@@ -6,26 +6,32 @@ import (
 	"strings"
 
 	"github.com/quicdb/quic-cli/internal/api"
+	"github.com/quicdb/quic-cli/internal/prompt"
 	"github.com/quicdb/quic-cli/internal/userconfig"
 )
 
 // ResolveCluster resolves which cluster ID to use based on:
-// 1. Explicit flag value (returns immediately if provided)
+// 1. Explicit flag value (ID, name, subdomain or unique ID prefix)
 // 2. Single cluster auto-selection
 // 3. Config file selection (if valid)
-// 4. Returns error with cluster list for user selection
-func ResolveCluster(ctx context.Context, flagClusterID string, client *api.Client) (string, error) {
-	// If cluster ID provided via flag, use it directly
-	if flagClusterID != "" {
-		return flagClusterID, nil
-	}
-
+// 4. Interactive picker when attached to a terminal
+// 5. Returns error with cluster list for user selection
+func ResolveCluster(ctx context.Context, flagCluster string, client *api.Client) (string, error) {
 	// Fetch all clusters
 	clusters, err := client.ListClusters(ctx)
 	if err != nil {
 		return "", fmt.Errorf("failed to fetch clusters: %w", err)
 	}
 
+	// If a cluster was provided via flag, it must match exactly one cluster
+	if flagCluster != "" {
+		c, err := Match(clusters, flagCluster)
+		if err != nil {
+			return "", err
+		}
+		return c.ID, nil
+	}
+
 	if len(clusters) == 0 {
 		return "", fmt.Errorf("no clusters found. Please create a cluster in the dashboard first")
 	}
@@ -44,12 +50,26 @@ func ResolveCluster(ctx context.Context, flagClusterID string, client *api.Clien
 		}
 	}
 
-	// No valid selected cluster - return error with formatted table
-	table := FormatClusterTable(clusters)
-	return "", fmt.Errorf("please select a cluster:\n\n" +
-		"  Use --cluster flag:         --cluster=<cluster-id>\n" +
-		"  Or set default cluster:     quic config cluster <cluster-id>\n\n" +
-		"Available clusters:\n\n%s", table)
+	// No valid selected cluster - let the user pick one if we can ask
+	if prompt.IsInteractive() {
+		return pickCluster(clusters)
+	}
+
+	// Otherwise return error listing the available clusters
+	return "", &SelectionError{Clusters: clusters}
+}
+
+// SelectionError is returned when several clusters exist and none was chosen.
+// It carries the candidate clusters so callers can render them in any format.
+type SelectionError struct {
+	Clusters []api.Cluster
+}
+
+func (e *SelectionError) Error() string {
+	return fmt.Sprintf("please select a cluster:\n\n"+
+		"  Use --cluster flag:         --cluster=<cluster>\n"+
+		"  Or set default cluster:     quic clusters use <cluster>\n\n"+
+		"Available clusters:\n\n%s", FormatClusterTable(e.Clusters, ""))
 }
 
 // isValidCluster checks if the given cluster ID exists in the list
@@ -62,16 +82,18 @@ func isValidCluster(clusterID string, clusters []api.Cluster) bool {
 	return false
 }
 
-// FormatClusterTable formats a list of clusters as a table string
-func FormatClusterTable(clusters []api.Cluster) string {
+// FormatClusterTable formats a list of clusters as a table string. The
+// cluster matching selectedID, if any, is marked with an asterisk.
+func FormatClusterTable(clusters []api.Cluster, selectedID string) string {
 	var b strings.Builder
 
 	// Header
-	b.WriteString(fmt.Sprintf("  %-36s %-25s %-15s %-20s\n",
-		"Cluster ID", "Name", "Region", "Database"))
-	b.WriteString(fmt.Sprintf("  %-36s %-25s %-15s %-20s\n",
+	b.WriteString(fmt.Sprintf("  %-36s %-25s %-20s %-15s %-20s\n",
+		"Cluster ID", "Name", "Subdomain", "Region", "Database"))
+	b.WriteString(fmt.Sprintf("  %-36s %-25s %-20s %-15s %-20s\n",
 		"------------------------------------",
 		"-------------------------",
+		"--------------------",
 		"---------------",
 		"--------------------"))
 
@@ -81,12 +103,20 @@ func FormatClusterTable(clusters []api.Cluster) string {
 		if name == "" {
 			name = "-"
 		}
+		subdomain := cluster.Subdomain
+		if subdomain == "" {
+			subdomain = "-"
+		}
 		database := cluster.SelectedDatabase
 		if database == "" {
 			database = "-"
 		}
-		b.WriteString(fmt.Sprintf("  %-36s %-25s %-15s %-20s\n",
-			cluster.ID, name, cluster.Region, database))
+		marker := " "
+		if selectedID != "" && cluster.ID == selectedID {
+			marker = "*"
+		}
+		b.WriteString(fmt.Sprintf("%s %-36s %-25s %-20s %-15s %-20s\n",
+			marker, cluster.ID, name, subdomain, cluster.Region, database))
 	}
 
 	return b.String()
@@ -1,5 +1,7 @@
 package config
 
+import "fmt"
+
 // These values are injected at build time via -ldflags
 var (
 	ClientID     = ""
@@ -7,6 +9,10 @@ var (
 	AuthorizeURL = "http://localhost:5173/oauth/authorize"
 	StytchURL    = "https://test.stytch.com"
 	APIURL       = "http://localhost:8080"
+
+	// JWKSURL and Issuer default to the Stytch project's values when empty
+	JWKSURL = ""
+	Issuer  = ""
 )
 
 // Config holds all configuration values
@@ -16,15 +22,28 @@ type Config struct {
 	AuthorizeURL string
 	StytchURL    string
 	APIURL       string
+	JWKSURL      string
+	Issuer       string
 }
 
 // Get returns the current configuration
 func Get() *Config {
-	return &Config{
+	cfg := &Config{
 		ClientID:     ClientID,
 		ProjectID:    ProjectID,
 		AuthorizeURL: AuthorizeURL,
 		StytchURL:    StytchURL,
 		APIURL:       APIURL,
+		JWKSURL:      JWKSURL,
+		Issuer:       Issuer,
+	}
+
+	if cfg.JWKSURL == "" {
+		cfg.JWKSURL = fmt.Sprintf("%s/v1/sessions/jwks/%s", cfg.StytchURL, cfg.ProjectID)
+	}
+	if cfg.Issuer == "" {
+		cfg.Issuer = fmt.Sprintf("stytch.com/%s", cfg.ProjectID)
 	}
+
+	return cfg
 }
@@ -0,0 +1,173 @@
+package dotenv
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// assignment matches the start of a KEY=value line, optionally prefixed with
+// export. The groups are the prefix, the key and the value.
+var assignment = regexp.MustCompile(`^(\s*(?:export\s+)?)([A-Za-z_][A-Za-z0-9_.]*)\s*=(.*)$`)
+
+// Set assigns value to key in the dotenv file at path. An existing assignment
+// is replaced in place; comments, ordering and other keys are preserved. The
+// file is created with 0600 permissions if it does not exist.
+func Set(path, key, value string) error {
+	lines, mode, err := readLines(path)
+	if err != nil {
+		return err
+	}
+
+	entry := key + "=" + Quote(value)
+	start, end := find(lines, key)
+	if start >= 0 {
+		// Keep an export prefix so the file still works when sourced
+		prefix := assignment.FindStringSubmatch(lines[start])[1]
+		entry = prefix + entry
+		lines = append(lines[:start], append([]string{entry}, lines[end:]...)...)
+	} else {
+		lines = append(lines, entry)
+	}
+
+	return writeLines(path, lines, mode)
+}
+
+// Unset removes the assignment for key from the dotenv file at path. It
+// reports whether the key was present; a missing file is not an error.
+func Unset(path, key string) (bool, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return false, nil
+	}
+
+	lines, mode, err := readLines(path)
+	if err != nil {
+		return false, err
+	}
+
+	start, end := find(lines, key)
+	if start < 0 {
+		return false, nil
+	}
+
+	lines = append(lines[:start], lines[end:]...)
+	return true, writeLines(path, lines, mode)
+}
+
+// Quote quotes s for a dotenv file. Single quotes are preferred because
+// loaders neither expand variables nor process escapes inside them; values
+// containing a single quote or newline fall back to double quotes, with $
+// escaped so loaders that expand variables there leave the value intact.
+func Quote(s string) string {
+	if !strings.ContainsAny(s, "'\n") {
+		return "'" + s + "'"
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, `$`, `\$`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// find returns the line range [start, end) holding the last assignment of key,
+// including continuation lines of a multi-line quoted value, or -1 if absent
+func find(lines []string, key string) (int, int) {
+	start, end := -1, -1
+	for i := 0; i < len(lines); i++ {
+		m := assignment.FindStringSubmatch(lines[i])
+		if m == nil {
+			continue
+		}
+
+		last := i + valueSpan(lines[i:], strings.TrimSpace(m[3]))
+		if m[2] == key {
+			start, end = i, last
+		}
+		i = last - 1
+	}
+	return start, end
+}
+
+// valueSpan returns how many lines a value starting on the first line occupies
+func valueSpan(lines []string, value string) int {
+	if value == "" || (value[0] != '"' && value[0] != '\'') {
+		return 1
+	}
+
+	quote := value[0]
+	if closes(value[1:], quote) {
+		return 1
+	}
+	for n := 1; n < len(lines); n++ {
+		if closes(lines[n], quote) {
+			return n + 1
+		}
+	}
+	// Unterminated quote - treat it as a single line rather than eating the file
+	return 1
+}
+
+// closes reports whether s contains an unescaped closing quote
+func closes(s string, quote byte) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && quote == '"' {
+			i++
+			continue
+		}
+		if s[i] == quote {
+			return true
+		}
+	}
+	return false
+}
+
+func readLines(path string) ([]string, os.FileMode, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, 0600, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	content := strings.TrimSuffix(string(data), "\n")
+	if content == "" {
+		return nil, info.Mode().Perm(), nil
+	}
+	return strings.Split(content, "\n"), info.Mode().Perm(), nil
+}
+
+// writeLines replaces the file atomically so a failed write never truncates it
+func writeLines(path string, lines []string, mode os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	content := strings.Join(lines, "\n")
+	if content != "" {
+		content += "\n"
+	}
+
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set permissions on %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
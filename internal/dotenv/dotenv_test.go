@@ -0,0 +1,234 @@
+package dotenv
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "postgres://u:p@host/db", want: `'postgres://u:p@host/db'`},
+		{in: "pa$word", want: `'pa$word'`},
+		{in: `a\b"c`, want: `'a\b"c'`},
+		{in: "it's", want: `"it's"`},
+		{in: "it's $HOME", want: `"it's \$HOME"`},
+		{in: `it's "a\b"`, want: `"it's \"a\\b\""`},
+		{in: "one\ntwo", want: `"one\ntwo"`},
+	}
+
+	for _, tt := range tests {
+		if got := Quote(tt.in); got != tt.want {
+			t.Errorf("Quote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		initial string
+		key     string
+		value   string
+		want    string
+	}{
+		{
+			name:  "new file",
+			key:   "DATABASE_URL",
+			value: "postgres://a",
+			want:  "DATABASE_URL='postgres://a'\n",
+		},
+		{
+			name:    "append",
+			initial: "# app settings\nPORT=8080\n",
+			key:     "DATABASE_URL",
+			value:   "postgres://a",
+			want:    "# app settings\nPORT=8080\nDATABASE_URL='postgres://a'\n",
+		},
+		{
+			name:    "replace in place",
+			initial: "# db\nDATABASE_URL=old\nPORT=8080\n",
+			key:     "DATABASE_URL",
+			value:   "postgres://a",
+			want:    "# db\nDATABASE_URL='postgres://a'\nPORT=8080\n",
+		},
+		{
+			name:    "replace last of duplicates",
+			initial: "DATABASE_URL=one\nPORT=8080\nDATABASE_URL=two\n",
+			key:     "DATABASE_URL",
+			value:   "three",
+			want:    "DATABASE_URL=one\nPORT=8080\nDATABASE_URL='three'\n",
+		},
+		{
+			name:    "keeps export prefix",
+			initial: "export DATABASE_URL=old\nexport PORT=8080\n",
+			key:     "DATABASE_URL",
+			value:   "postgres://a",
+			want:    "export DATABASE_URL='postgres://a'\nexport PORT=8080\n",
+		},
+		{
+			name:    "key prefix is not a match",
+			initial: "DATABASE_URL_RO=ro\n",
+			key:     "DATABASE_URL",
+			value:   "rw",
+			want:    "DATABASE_URL_RO=ro\nDATABASE_URL='rw'\n",
+		},
+		{
+			name:    "replace multi-line value",
+			initial: "CERT=\"-----BEGIN\nabc\n-----END\"\nPORT=8080\n",
+			key:     "CERT",
+			value:   "new",
+			want:    "CERT='new'\nPORT=8080\n",
+		},
+		{
+			name:    "assignment inside multi-line value is not a match",
+			initial: "NOTE='first\nDATABASE_URL=inside\nlast'\n",
+			key:     "DATABASE_URL",
+			value:   "outside",
+			want:    "NOTE='first\nDATABASE_URL=inside\nlast'\nDATABASE_URL='outside'\n",
+		},
+		{
+			name:    "escaped quote does not close value",
+			initial: "MSG=\"say \\\"hi\\\"\nDATABASE_URL=inside\"\nPORT=8080\n",
+			key:     "MSG",
+			value:   "bye",
+			want:    "MSG='bye'\nPORT=8080\n",
+		},
+		{
+			name:    "unterminated quote stays on one line",
+			initial: "BROKEN=\"oops\nDATABASE_URL=old\n",
+			key:     "DATABASE_URL",
+			value:   "new",
+			want:    "BROKEN=\"oops\nDATABASE_URL='new'\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".env")
+			if tt.initial != "" {
+				if err := os.WriteFile(path, []byte(tt.initial), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := Set(path, tt.key, tt.value); err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Set() wrote %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetFileMode(t *testing.T) {
+	dir := t.TempDir()
+
+	created := filepath.Join(dir, "created.env")
+	if err := Set(created, "KEY", "value"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if info, err := os.Stat(created); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("new file mode = %v, %v, want 0600", info.Mode().Perm(), err)
+	}
+
+	existing := filepath.Join(dir, "existing.env")
+	if err := os.WriteFile(existing, []byte("KEY=old\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := Set(existing, "KEY", "value"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if info, err := os.Stat(existing); err != nil || info.Mode().Perm() != 0640 {
+		t.Errorf("existing file mode = %v, %v, want 0640", info.Mode().Perm(), err)
+	}
+}
+
+func TestUnset(t *testing.T) {
+	tests := []struct {
+		name    string
+		initial string
+		key     string
+		want    string
+		removed bool
+	}{
+		{
+			name:    "present",
+			initial: "# db\nDATABASE_URL=old\nPORT=8080\n",
+			key:     "DATABASE_URL",
+			want:    "# db\nPORT=8080\n",
+			removed: true,
+		},
+		{
+			name:    "export prefix",
+			initial: "export DATABASE_URL=old\nexport PORT=8080\n",
+			key:     "DATABASE_URL",
+			want:    "export PORT=8080\n",
+			removed: true,
+		},
+		{
+			name:    "multi-line value",
+			initial: "PORT=8080\nCERT='-----BEGIN\nabc\n-----END'\nHOST=localhost\n",
+			key:     "CERT",
+			want:    "PORT=8080\nHOST=localhost\n",
+			removed: true,
+		},
+		{
+			name:    "only key",
+			initial: "DATABASE_URL=old\n",
+			key:     "DATABASE_URL",
+			want:    "",
+			removed: true,
+		},
+		{
+			name:    "absent",
+			initial: "PORT=8080\n",
+			key:     "DATABASE_URL",
+			want:    "PORT=8080\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".env")
+			if err := os.WriteFile(path, []byte(tt.initial), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			removed, err := Unset(path, tt.key)
+			if err != nil {
+				t.Fatalf("Unset() error = %v", err)
+			}
+			if removed != tt.removed {
+				t.Errorf("Unset() = %v, want %v", removed, tt.removed)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Unset() left %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnsetMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+
+	removed, err := Unset(path, "DATABASE_URL")
+	if err != nil || removed {
+		t.Fatalf("Unset() = %v, %v, want false, nil", removed, err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Unset() created %s", path)
+	}
+}
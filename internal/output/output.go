@@ -0,0 +1,54 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format is the rendering used for command output
+type Format string
+
+const (
+	Table Format = "table"
+	JSON  Format = "json"
+	YAML  Format = "yaml"
+)
+
+// Formats lists the supported output formats
+var Formats = []Format{Table, JSON, YAML}
+
+// ParseFormat validates an --output flag value
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if string(f) == s {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported output format %q (expected table, json or yaml)", s)
+}
+
+// Print renders v in the given format. For Table, the table function is
+// responsible for writing the human-readable representation.
+func Print(w io.Writer, format Format, v any, table func(w io.Writer) error) error {
+	switch format {
+	case JSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case YAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return enc.Close()
+	default:
+		if table == nil {
+			return nil
+		}
+		return table(w)
+	}
+}
@@ -0,0 +1,324 @@
+package pgprobe
+
+import (
+	"bufio"
+	"context"
+	"crypto/md5"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Protocol constants from the PostgreSQL frontend/backend protocol
+const (
+	protocolVersion = 196608   // 3.0
+	sslRequestCode  = 80877103 // SSLRequest magic number
+)
+
+// Config identifies the server and credentials to probe
+type Config struct {
+	Host     string
+	Port     int
+	User     string
+	Password string
+	Database string
+}
+
+// ServerError is an ErrorResponse sent by the server during startup
+type ServerError struct {
+	Severity string
+	Code     string
+	Message  string
+}
+
+func (e *ServerError) Error() string {
+	return fmt.Sprintf("%s: %s (SQLSTATE %s)", e.Severity, e.Message, e.Code)
+}
+
+// AuthError is a failure of the authentication exchange itself, such as an
+// unsupported method or a server signature that does not match
+type AuthError struct {
+	Err error
+}
+
+func (e *AuthError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *AuthError) Unwrap() error {
+	return e.Err
+}
+
+// WaitReady probes the server every interval until it accepts a connection
+// or ctx is done. On timeout the last probe error is returned. Errors that
+// waiting cannot fix, such as rejected credentials, are returned at once.
+func WaitReady(ctx context.Context, cfg Config, interval time.Duration) error {
+	for {
+		err := Probe(ctx, cfg)
+		if err == nil {
+			return nil
+		}
+		if !retryable(err) {
+			return fmt.Errorf("cannot connect to branch: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("branch did not become ready: %w", err)
+		case <-time.After(interval):
+		}
+	}
+}
+
+// retryable reports whether a probe error may go away while the server starts:
+// connection and I/O failures, and the server saying it is starting up
+func retryable(err error) bool {
+	var serverErr *ServerError
+	if errors.As(err, &serverErr) {
+		return serverErr.Code == "57P03" // cannot_connect_now
+	}
+	var authErr *AuthError
+	return !errors.As(err, &authErr)
+}
+
+// Probe opens a connection and completes the startup and authentication
+// handshake. It returns nil once the server reports it is ready for queries.
+func Probe(ctx context.Context, cfg Config) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(30 * time.Second))
+	}
+
+	conn, err = negotiateTLS(conn, cfg.Host)
+	if err != nil {
+		return err
+	}
+
+	p := &prober{conn: conn, r: bufio.NewReader(conn), cfg: cfg}
+	if err := p.startup(); err != nil {
+		return err
+	}
+
+	// Politely end the session
+	p.send('X', nil)
+	return nil
+}
+
+// negotiateTLS upgrades the connection when the server supports TLS. Like
+// libpq's default sslmode=prefer, the certificate is not verified: the probe
+// only checks readiness and uses the same transport a default client would.
+func negotiateTLS(conn net.Conn, host string) (net.Conn, error) {
+	req := make([]byte, 8)
+	binary.BigEndian.PutUint32(req[0:4], 8)
+	binary.BigEndian.PutUint32(req[4:8], sslRequestCode)
+	if _, err := conn.Write(req); err != nil {
+		return nil, err
+	}
+
+	resp := make([]byte, 1)
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return nil, err
+	}
+
+	if resp[0] != 'S' {
+		return conn, nil
+	}
+
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: true,
+	})
+	if err := tlsConn.Handshake(); err != nil {
+		return nil, fmt.Errorf("TLS handshake failed: %w", err)
+	}
+	return tlsConn, nil
+}
+
+// prober drives the startup message flow on an open connection
+type prober struct {
+	conn  net.Conn
+	r     *bufio.Reader
+	cfg   Config
+	scram *scramClient
+}
+
+func (p *prober) startup() error {
+	var params []byte
+	for _, kv := range [][2]string{{"user", p.cfg.User}, {"database", p.cfg.Database}} {
+		params = append(params, kv[0]...)
+		params = append(params, 0)
+		params = append(params, kv[1]...)
+		params = append(params, 0)
+	}
+	params = append(params, 0)
+
+	msg := make([]byte, 8, 8+len(params))
+	binary.BigEndian.PutUint32(msg[0:4], uint32(8+len(params)))
+	binary.BigEndian.PutUint32(msg[4:8], protocolVersion)
+	msg = append(msg, params...)
+	if _, err := p.conn.Write(msg); err != nil {
+		return err
+	}
+
+	for {
+		typ, body, err := p.receive()
+		if err != nil {
+			return err
+		}
+
+		switch typ {
+		case 'R':
+			if err := p.authenticate(body); err != nil {
+				return err
+			}
+		case 'E':
+			return parseError(body)
+		case 'Z':
+			return nil
+		default:
+			// ParameterStatus, BackendKeyData and notices need no handling
+		}
+	}
+}
+
+// authenticate answers an Authentication* request
+func (p *prober) authenticate(body []byte) error {
+	if len(body) < 4 {
+		return &AuthError{Err: errors.New("malformed authentication message")}
+	}
+	code := binary.BigEndian.Uint32(body[0:4])
+	data := body[4:]
+
+	switch code {
+	case 0: // AuthenticationOk
+		return nil
+	case 3: // AuthenticationCleartextPassword
+		return p.send('p', append([]byte(p.cfg.Password), 0))
+	case 5: // AuthenticationMD5Password
+		if len(data) < 4 {
+			return &AuthError{Err: errors.New("malformed MD5 authentication request")}
+		}
+		inner := md5Hex(p.cfg.Password + p.cfg.User)
+		return p.send('p', append([]byte("md5"+md5Hex(inner+string(data[:4]))), 0))
+	case 10: // AuthenticationSASL
+		if !containsMechanism(data, "SCRAM-SHA-256") {
+			return &AuthError{Err: errors.New("server does not offer SCRAM-SHA-256 authentication")}
+		}
+		scram, err := newSCRAMClient(p.cfg.Password)
+		if err != nil {
+			return err
+		}
+		p.scram = scram
+
+		first := scram.clientFirst()
+		msg := append([]byte("SCRAM-SHA-256"), 0)
+		msg = binary.BigEndian.AppendUint32(msg, uint32(len(first)))
+		msg = append(msg, first...)
+		return p.send('p', msg)
+	case 11: // AuthenticationSASLContinue
+		if p.scram == nil {
+			return &AuthError{Err: errors.New("unexpected SASL continuation")}
+		}
+		final, err := p.scram.clientFinal(string(data))
+		if err != nil {
+			return &AuthError{Err: err}
+		}
+		return p.send('p', []byte(final))
+	case 12: // AuthenticationSASLFinal
+		if p.scram == nil {
+			return &AuthError{Err: errors.New("unexpected SASL completion")}
+		}
+		if err := p.scram.verifyServerFinal(string(data)); err != nil {
+			return &AuthError{Err: err}
+		}
+		return nil
+	default:
+		return &AuthError{Err: fmt.Errorf("unsupported authentication method (code %d)", code)}
+	}
+}
+
+func (p *prober) send(typ byte, body []byte) error {
+	msg := make([]byte, 5, 5+len(body))
+	msg[0] = typ
+	binary.BigEndian.PutUint32(msg[1:5], uint32(4+len(body)))
+	msg = append(msg, body...)
+	_, err := p.conn.Write(msg)
+	return err
+}
+
+func (p *prober) receive() (byte, []byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(p.r, header); err != nil {
+		return 0, nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header[1:5])
+	if length < 4 || length > 1<<20 {
+		return 0, nil, fmt.Errorf("invalid message length %d", length)
+	}
+
+	body := make([]byte, length-4)
+	if _, err := io.ReadFull(p.r, body); err != nil {
+		return 0, nil, err
+	}
+	return header[0], body, nil
+}
+
+// parseError decodes an ErrorResponse body into a ServerError
+func parseError(body []byte) error {
+	e := &ServerError{}
+	for len(body) > 0 && body[0] != 0 {
+		field := body[0]
+		end := strings.IndexByte(string(body[1:]), 0)
+		if end < 0 {
+			break
+		}
+		value := string(body[1 : 1+end])
+		body = body[2+end:]
+
+		switch field {
+		case 'S':
+			e.Severity = value
+		case 'C':
+			e.Code = value
+		case 'M':
+			e.Message = value
+		}
+	}
+
+	if e.Message == "" {
+		return errors.New("server returned an error during startup")
+	}
+	return e
+}
+
+// containsMechanism reports whether a NUL-separated SASL mechanism list
+// includes name
+func containsMechanism(list []byte, name string) bool {
+	for _, m := range strings.Split(string(list), "\x00") {
+		if m == name {
+			return true
+		}
+	}
+	return false
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,114 @@
+package pgprobe
+
+import (
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// scramClient implements the client side of SCRAM-SHA-256 (RFC 7677) as used
+// by PostgreSQL, without channel binding
+type scramClient struct {
+	password    string
+	clientNonce string
+	firstBare   string
+	authMessage string
+	saltedPass  []byte
+}
+
+func newSCRAMClient(password string) (*scramClient, error) {
+	nonce := make([]byte, 18)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate SCRAM nonce: %w", err)
+	}
+	return &scramClient{
+		password:    password,
+		clientNonce: base64.StdEncoding.EncodeToString(nonce),
+	}, nil
+}
+
+// clientFirst returns the client-first-message. PostgreSQL takes the user
+// name from the startup message, so it is left empty here.
+func (c *scramClient) clientFirst() string {
+	c.firstBare = "n=,r=" + c.clientNonce
+	return "n,," + c.firstBare
+}
+
+// clientFinal processes the server-first-message and returns the
+// client-final-message carrying the proof
+func (c *scramClient) clientFinal(serverFirst string) (string, error) {
+	attrs := parseSCRAMAttributes(serverFirst)
+
+	nonce := attrs["r"]
+	if !strings.HasPrefix(nonce, c.clientNonce) {
+		return "", fmt.Errorf("SCRAM server nonce does not extend client nonce")
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(attrs["s"])
+	if err != nil {
+		return "", fmt.Errorf("invalid SCRAM salt: %w", err)
+	}
+
+	iterations, err := strconv.Atoi(attrs["i"])
+	if err != nil || iterations <= 0 {
+		return "", fmt.Errorf("invalid SCRAM iteration count")
+	}
+
+	c.saltedPass, err = pbkdf2.Key(sha256.New, c.password, salt, iterations, sha256.Size)
+	if err != nil {
+		return "", fmt.Errorf("failed to derive SCRAM key: %w", err)
+	}
+
+	withoutProof := "c=biws,r=" + nonce
+	c.authMessage = c.firstBare + "," + serverFirst + "," + withoutProof
+
+	clientKey := hmacSHA256(c.saltedPass, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+	signature := hmacSHA256(storedKey[:], c.authMessage)
+
+	proof := make([]byte, len(clientKey))
+	for i := range clientKey {
+		proof[i] = clientKey[i] ^ signature[i]
+	}
+
+	return withoutProof + ",p=" + base64.StdEncoding.EncodeToString(proof), nil
+}
+
+// verifyServerFinal checks the server signature so a server that does not
+// know the password cannot pretend authentication succeeded
+func (c *scramClient) verifyServerFinal(serverFinal string) error {
+	attrs := parseSCRAMAttributes(serverFinal)
+	if e, ok := attrs["e"]; ok {
+		return fmt.Errorf("SCRAM authentication failed: %s", e)
+	}
+
+	serverKey := hmacSHA256(c.saltedPass, "Server Key")
+	expected := hmacSHA256(serverKey, c.authMessage)
+
+	got, err := base64.StdEncoding.DecodeString(attrs["v"])
+	if err != nil || !hmac.Equal(got, expected) {
+		return fmt.Errorf("SCRAM server signature mismatch")
+	}
+	return nil
+}
+
+func parseSCRAMAttributes(msg string) map[string]string {
+	attrs := make(map[string]string)
+	for _, part := range strings.Split(msg, ",") {
+		if k, v, ok := strings.Cut(part, "="); ok {
+			attrs[k] = v
+		}
+	}
+	return attrs
+}
+
+func hmacSHA256(key []byte, msg string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(msg))
+	return h.Sum(nil)
+}
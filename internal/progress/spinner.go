@@ -0,0 +1,88 @@
+package progress
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+var frames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// Spinner shows an animated status line on stderr. When stderr is not a
+// terminal it prints each distinct message once instead of animating.
+type Spinner struct {
+	mu      sync.Mutex
+	message string
+	tty     bool
+	done    chan struct{}
+	stopped sync.WaitGroup
+}
+
+// NewSpinner creates and starts a spinner with the given message
+func NewSpinner(message string) *Spinner {
+	s := &Spinner{
+		message: message,
+		tty:     term.IsTerminal(int(os.Stderr.Fd())),
+		done:    make(chan struct{}),
+	}
+
+	if !s.tty {
+		fmt.Fprintln(os.Stderr, message)
+		return s
+	}
+
+	s.stopped.Add(1)
+	go s.run()
+	return s
+}
+
+// Update replaces the spinner message
+func (s *Spinner) Update(message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if message == s.message {
+		return
+	}
+	s.message = message
+	if !s.tty {
+		fmt.Fprintln(os.Stderr, message)
+	}
+}
+
+// Stop halts the animation and clears the status line
+func (s *Spinner) Stop() {
+	select {
+	case <-s.done:
+		return
+	default:
+		close(s.done)
+	}
+
+	s.stopped.Wait()
+	if s.tty {
+		fmt.Fprint(os.Stderr, "\r\x1b[K")
+	}
+}
+
+func (s *Spinner) run() {
+	defer s.stopped.Done()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for i := 0; ; i++ {
+		s.mu.Lock()
+		fmt.Fprintf(os.Stderr, "\r\x1b[K%s %s", frames[i%len(frames)], s.message)
+		s.mu.Unlock()
+
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
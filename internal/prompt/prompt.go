@@ -0,0 +1,207 @@
+package prompt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// ErrCancelled is returned when the user aborts a prompt
+var ErrCancelled = errors.New("cancelled")
+
+// maxVisible is the number of options shown at once by Select
+const maxVisible = 10
+
+// IsInteractive reports whether stdin and stdout are both attached to a terminal
+func IsInteractive() bool {
+	return term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// Select shows an arrow-key menu over options and returns the index of the
+// chosen one. Typing filters the options with a fuzzy subsequence match.
+func Select(title string, options []string) (int, error) {
+	if len(options) == 0 {
+		return -1, fmt.Errorf("nothing to select")
+	}
+
+	fd := int(os.Stdin.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return -1, fmt.Errorf("failed to enable raw terminal mode: %w", err)
+	}
+	defer term.Restore(fd, state)
+
+	s := &selector{title: title, options: options}
+	s.filter()
+	s.render()
+	defer s.clear()
+
+	buf := make([]byte, 16)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return -1, fmt.Errorf("failed to read input: %w", err)
+		}
+
+		for i := 0; i < n; i++ {
+			switch b := buf[i]; {
+			case b == 3 || (b == 27 && i+1 == n): // Ctrl-C or a lone Esc
+				return -1, ErrCancelled
+			case b == '\r' || b == '\n':
+				if len(s.matches) == 0 {
+					continue
+				}
+				return s.matches[s.cursor], nil
+			case b == 27 && i+2 < n && buf[i+1] == '[': // Arrow keys
+				switch buf[i+2] {
+				case 'A':
+					s.move(-1)
+				case 'B':
+					s.move(1)
+				}
+				i += 2
+			case b == 16: // Ctrl-P
+				s.move(-1)
+			case b == 14: // Ctrl-N
+				s.move(1)
+			case b == 127 || b == 8: // Backspace
+				if s.query != "" {
+					s.query = s.query[:len(s.query)-1]
+					s.filter()
+				}
+			case b >= 32 && b < 127:
+				s.query += string(b)
+				s.filter()
+			}
+		}
+
+		s.render()
+	}
+}
+
+// Confirm asks a yes/no question and returns the answer. An empty answer
+// yields defaultYes.
+func Confirm(question string, defaultYes bool) (bool, error) {
+	hint := "[y/N]"
+	if defaultYes {
+		hint = "[Y/n]"
+	}
+	fmt.Printf("%s %s ", question, hint)
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false, fmt.Errorf("failed to read input: %w", err)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "":
+		return defaultYes, nil
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// Secret reads a line from the terminal without echoing it. The label is
+// written to stderr so it does not end up in redirected output.
+func Secret(label string) (string, error) {
+	fmt.Fprint(os.Stderr, label)
+	value, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read input: %w", err)
+	}
+	return string(value), nil
+}
+
+// selector holds the state of an active Select menu
+type selector struct {
+	title   string
+	options []string
+	query   string
+	matches []int // indexes into options
+	cursor  int   // position within matches
+	offset  int   // first visible match
+	lines   int   // lines drawn by the last render
+}
+
+// filter recomputes matches for the current query
+func (s *selector) filter() {
+	s.matches = s.matches[:0]
+	for i, option := range s.options {
+		if fuzzyMatch(option, s.query) {
+			s.matches = append(s.matches, i)
+		}
+	}
+	s.cursor = 0
+	s.offset = 0
+}
+
+// move shifts the cursor, wrapping around and keeping it visible
+func (s *selector) move(delta int) {
+	if len(s.matches) == 0 {
+		return
+	}
+	s.cursor = (s.cursor + delta + len(s.matches)) % len(s.matches)
+	if s.cursor < s.offset {
+		s.offset = s.cursor
+	}
+	if s.cursor >= s.offset+maxVisible {
+		s.offset = s.cursor - maxVisible + 1
+	}
+}
+
+// render redraws the menu in place. Raw mode requires explicit \r\n.
+func (s *selector) render() {
+	s.clear()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s (type to filter, ↑/↓ to move, enter to select)\r\n", s.title)
+	fmt.Fprintf(&b, "> %s\r\n", s.query)
+	lines := 2
+
+	if len(s.matches) == 0 {
+		b.WriteString("  no matches\r\n")
+		lines++
+	}
+
+	end := min(s.offset+maxVisible, len(s.matches))
+	for i := s.offset; i < end; i++ {
+		marker := "  "
+		if i == s.cursor {
+			marker = "\x1b[7m>"
+		}
+		fmt.Fprintf(&b, "%s %s\x1b[0m\r\n", marker, s.options[s.matches[i]])
+		lines++
+	}
+
+	os.Stdout.WriteString(b.String())
+	s.lines = lines
+}
+
+// clear erases the lines drawn by the previous render
+func (s *selector) clear() {
+	if s.lines > 0 {
+		fmt.Fprintf(os.Stdout, "\x1b[%dA\r\x1b[J", s.lines)
+		s.lines = 0
+	}
+}
+
+// fuzzyMatch reports whether the characters of query appear in order in s,
+// ignoring case
+func fuzzyMatch(s, query string) bool {
+	s = strings.ToLower(s)
+	for _, r := range strings.ToLower(query) {
+		i := strings.IndexRune(s, r)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(string(r)):]
+	}
+	return true
+}
@@ -0,0 +1,240 @@
+// Package retry retries HTTP requests that failed for transient reasons, with
+// jittered exponential backoff.
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Policy controls how failed requests are retried
+type Policy struct {
+	// MaxRetries is the number of retries after the first attempt; 0 disables retrying
+	MaxRetries int
+	// Timeout is the overall deadline: no retry starts later than this after
+	// the first attempt
+	Timeout time.Duration
+	// BaseDelay and MaxDelay bound the exponential backoff between attempts
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// DefaultPolicy is used unless SetPolicy is called
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxRetries: 3,
+		Timeout:    time.Minute,
+		BaseDelay:  500 * time.Millisecond,
+		MaxDelay:   15 * time.Second,
+	}
+}
+
+// MaxDuration bounds how long a request sent with this policy can take when
+// each attempt is limited to attemptTimeout. Retry-After delays beyond
+// MaxDelay are only bounded when Timeout is set.
+func (p Policy) MaxDuration(attemptTimeout time.Duration) time.Duration {
+	if p.Timeout > 0 {
+		return p.Timeout + attemptTimeout
+	}
+	retries := time.Duration(max(p.MaxRetries, 0))
+	return (retries+1)*attemptTimeout + retries*p.MaxDelay
+}
+
+var (
+	policyMu sync.Mutex
+	current  = DefaultPolicy()
+)
+
+// SetPolicy replaces the policy used by all clients from NewClient
+func SetPolicy(p Policy) {
+	policyMu.Lock()
+	defer policyMu.Unlock()
+	current = p
+}
+
+// CurrentPolicy returns the policy set with SetPolicy
+func CurrentPolicy() Policy {
+	policyMu.Lock()
+	defer policyMu.Unlock()
+	return current
+}
+
+type idempotentKey struct{}
+
+// Idempotent marks requests made with the returned context as safe to retry
+// even though their method is not idempotent, e.g. a client credentials grant
+func Idempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+// NewClient returns an HTTP client that retries with the current policy.
+// attemptTimeout bounds each attempt separately; 0 means no limit.
+func NewClient(attemptTimeout time.Duration) *http.Client {
+	return &http.Client{Transport: &Transport{AttemptTimeout: attemptTimeout}}
+}
+
+// Transport is an http.RoundTripper that retries transient failures:
+// 429 and 503 responses and connection failures for every request, and also
+// 502, 504 and timeouts or dropped connections for idempotent requests,
+// which may have reached the server already. Retry-After is honored.
+type Transport struct {
+	// Base performs the individual attempts; nil means http.DefaultTransport
+	Base http.RoundTripper
+	// AttemptTimeout bounds each attempt including reading the body; 0 means no limit
+	AttemptTimeout time.Duration
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	policy := CurrentPolicy()
+	idempotent := isIdempotent(req)
+	deadline := time.Now().Add(policy.Timeout)
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			// The previous attempt consumed the body, so rewind it
+			if req.Body != nil && req.Body != http.NoBody {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				req = req.Clone(req.Context())
+				req.Body = body
+			}
+		}
+
+		resp, err := t.attempt(req)
+		if attempt >= policy.MaxRetries || !shouldRetry(req, resp, err, idempotent) {
+			return resp, err
+		}
+		if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+			return resp, err
+		}
+
+		delay := backoff(policy, attempt)
+		if resp != nil {
+			if after, ok := retryAfter(resp); ok {
+				delay = after
+			}
+		}
+		if policy.Timeout > 0 && time.Now().Add(delay).After(deadline) {
+			return resp, err
+		}
+
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt performs a single request, bounded by AttemptTimeout
+func (t *Transport) attempt(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	if t.AttemptTimeout <= 0 {
+		return base.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.AttemptTimeout)
+	resp, err := base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// Keep the timeout running while the caller reads the body
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelBody releases the attempt's context once the body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	marked, _ := req.Context().Value(idempotentKey{}).(bool)
+	return marked
+}
+
+func shouldRetry(req *http.Request, resp *http.Response, err error, idempotent bool) bool {
+	if err != nil {
+		// Cancelled by the caller, not a transient failure
+		if req.Context().Err() != nil {
+			return false
+		}
+		if idempotent {
+			return true
+		}
+		// The request never left this machine if the connection was not established
+		var opErr *net.OpError
+		return errors.As(err, &opErr) && opErr.Op == "dial"
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent
+	}
+	return false
+}
+
+// backoff returns a random delay up to BaseDelay doubled per attempt, capped
+// at MaxDelay ("full jitter"), so concurrent clients spread out their retries
+func backoff(policy Policy, attempt int) time.Duration {
+	ceiling := policy.MaxDelay
+	if attempt < 30 {
+		ceiling = min(policy.BaseDelay<<attempt, policy.MaxDelay)
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return rand.N(ceiling) + 1
+}
+
+// retryAfter parses the Retry-After header, given in seconds or as an HTTP date
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
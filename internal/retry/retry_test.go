@@ -0,0 +1,398 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// setTestPolicy installs a policy with short delays for the duration of a test
+func setTestPolicy(t *testing.T, p Policy) {
+	t.Helper()
+	previous := CurrentPolicy()
+	SetPolicy(p)
+	t.Cleanup(func() { SetPolicy(previous) })
+}
+
+func fastPolicy() Policy {
+	return Policy{
+		MaxRetries: 3,
+		Timeout:    10 * time.Second,
+		BaseDelay:  time.Millisecond,
+		MaxDelay:   5 * time.Millisecond,
+	}
+}
+
+// countingTransport counts the attempts that reach its base transport
+type countingTransport struct {
+	base     http.RoundTripper
+	attempts atomic.Int32
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.attempts.Add(1)
+	return c.base.RoundTrip(req)
+}
+
+func TestShouldRetry(t *testing.T) {
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	readErr := &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}
+
+	tests := []struct {
+		name       string
+		status     int
+		err        error
+		idempotent bool
+		want       bool
+	}{
+		{name: "429 idempotent", status: 429, idempotent: true, want: true},
+		{name: "429 non-idempotent", status: 429, want: true},
+		{name: "503 non-idempotent", status: 503, want: true},
+		{name: "502 idempotent", status: 502, idempotent: true, want: true},
+		{name: "502 non-idempotent", status: 502},
+		{name: "504 idempotent", status: 504, idempotent: true, want: true},
+		{name: "504 non-idempotent", status: 504},
+		{name: "500 idempotent", status: 500, idempotent: true},
+		{name: "404 idempotent", status: 404, idempotent: true},
+		{name: "200 idempotent", status: 200, idempotent: true},
+		{name: "dial error non-idempotent", err: dialErr, want: true},
+		{name: "read error idempotent", err: readErr, idempotent: true, want: true},
+		{name: "read error non-idempotent", err: readErr},
+		{name: "timeout non-idempotent", err: context.DeadlineExceeded},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp *http.Response
+			if tt.err == nil {
+				resp = &http.Response{StatusCode: tt.status}
+			}
+			if got := shouldRetry(req, resp, tt.err, tt.idempotent); got != tt.want {
+				t.Errorf("shouldRetry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShouldRetryCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodGet, "http://example.com", nil).WithContext(ctx)
+
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: context.Canceled}
+	if shouldRetry(req, nil, dialErr, true) {
+		t.Error("shouldRetry() = true after the caller cancelled, want false")
+	}
+}
+
+func TestTransportRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		idempotent   bool
+		status       int
+		wantAttempts int32
+	}{
+		{name: "GET 502", method: http.MethodGet, status: 502, wantAttempts: 2},
+		{name: "GET 503", method: http.MethodGet, status: 503, wantAttempts: 2},
+		{name: "GET 500", method: http.MethodGet, status: 500, wantAttempts: 1},
+		{name: "DELETE 504", method: http.MethodDelete, status: 504, wantAttempts: 2},
+		{name: "POST 502", method: http.MethodPost, status: 502, wantAttempts: 1},
+		{name: "POST 504", method: http.MethodPost, status: 504, wantAttempts: 1},
+		{name: "POST 503", method: http.MethodPost, status: 503, wantAttempts: 2},
+		{name: "POST 429", method: http.MethodPost, status: 429, wantAttempts: 2},
+		{name: "marked POST 502", method: http.MethodPost, idempotent: true, status: 502, wantAttempts: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTestPolicy(t, fastPolicy())
+
+			// Fails once, then succeeds
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if attempts.Add(1) == 1 {
+					w.WriteHeader(tt.status)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			ctx := context.Background()
+			if tt.idempotent {
+				ctx = Idempotent(ctx)
+			}
+			req, err := http.NewRequestWithContext(ctx, tt.method, server.URL, strings.NewReader("{}"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := NewClient(0).Do(req)
+			if err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			resp.Body.Close()
+
+			if got := attempts.Load(); got != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", got, tt.wantAttempts)
+			}
+			wantStatus := http.StatusOK
+			if tt.wantAttempts == 1 {
+				wantStatus = tt.status
+			}
+			if resp.StatusCode != wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, wantStatus)
+			}
+		})
+	}
+}
+
+func TestTransportStopsAfterMaxRetries(t *testing.T) {
+	setTestPolicy(t, fastPolicy())
+
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	resp, err := NewClient(0).Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", resp.StatusCode)
+	}
+	if got := attempts.Load(); got != 4 {
+		t.Errorf("attempts = %d, want 4", got)
+	}
+}
+
+func TestTransportRetriesDialErrorForPOST(t *testing.T) {
+	setTestPolicy(t, fastPolicy())
+
+	// Nothing listens on the address once the listener is closed
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	counter := &countingTransport{base: http.DefaultTransport}
+	client := &http.Client{Transport: &Transport{Base: counter}}
+
+	_, err = client.Post("http://"+addr, "application/json", strings.NewReader("{}"))
+	if err == nil {
+		t.Fatal("Post() error = nil, want connection refused")
+	}
+	if got := counter.attempts.Load(); got != 4 {
+		t.Errorf("attempts = %d, want 4", got)
+	}
+}
+
+func TestTransportRewindsBody(t *testing.T) {
+	setTestPolicy(t, fastPolicy())
+
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	resp, err := NewClient(0).Post(server.URL, "application/json", strings.NewReader(`{"name":"feature"}`))
+	if err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("status = %d, want 201", resp.StatusCode)
+	}
+	if len(bodies) != 3 {
+		t.Fatalf("attempts = %d, want 3", len(bodies))
+	}
+	for i, body := range bodies {
+		if body != `{"name":"feature"}` {
+			t.Errorf("attempt %d body = %q, want the original body", i+1, body)
+		}
+	}
+}
+
+func TestTransportDoesNotRetryUnrewindableBody(t *testing.T) {
+	setTestPolicy(t, fastPolicy())
+
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	// NewRequest only sets GetBody for bodies it knows how to replay
+	req, err := http.NewRequest(http.MethodPost, server.URL, io.NopCloser(strings.NewReader("{}")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := NewClient(0).Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if got := attempts.Load(); got != 1 {
+		t.Errorf("attempts = %d, want 1", got)
+	}
+}
+
+func TestTransportDeadline(t *testing.T) {
+	policy := fastPolicy()
+	policy.Timeout = time.Second
+	setTestPolicy(t, policy)
+
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	start := time.Now()
+	resp, err := NewClient(0).Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+
+	// Waiting two minutes would overrun the deadline, so the 429 is returned
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want 429", resp.StatusCode)
+	}
+	if got := attempts.Load(); got != 1 {
+		t.Errorf("attempts = %d, want 1", got)
+	}
+	if elapsed := time.Since(start); elapsed > policy.Timeout {
+		t.Errorf("Get() took %v, want it to give up without waiting", elapsed)
+	}
+}
+
+func TestTransportHonorsRetryAfter(t *testing.T) {
+	setTestPolicy(t, fastPolicy())
+
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	start := time.Now()
+	resp, err := NewClient(0).Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+	// The backoff alone would retry within milliseconds
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("Get() took %v, want at least the 1s from Retry-After", elapsed)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   time.Duration
+		wantOK bool
+	}{
+		{name: "absent"},
+		{name: "seconds", header: "30", want: 30 * time.Second, wantOK: true},
+		{name: "zero", header: "0", want: 0, wantOK: true},
+		{name: "negative", header: "-5"},
+		{name: "garbage", header: "soon"},
+		{name: "past date", header: time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), want: 0, wantOK: true},
+		{name: "future date", header: time.Now().Add(time.Minute).UTC().Format(http.TimeFormat), want: time.Minute, wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}}
+			if tt.header != "" {
+				resp.Header.Set("Retry-After", tt.header)
+			}
+
+			got, ok := retryAfter(resp)
+			if ok != tt.wantOK {
+				t.Fatalf("retryAfter() ok = %v, want %v", ok, tt.wantOK)
+			}
+			// HTTP dates have one-second resolution
+			if diff := got - tt.want; diff < -time.Second || diff > time.Second {
+				t.Errorf("retryAfter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := Policy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	for attempt, ceiling := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second} {
+		for range 50 {
+			if d := backoff(policy, attempt); d <= 0 || d > ceiling {
+				t.Fatalf("backoff(%d) = %v, want in (0, %v]", attempt, d, ceiling)
+			}
+		}
+	}
+
+	if d := backoff(policy, 100); d <= 0 || d > policy.MaxDelay {
+		t.Errorf("backoff(100) = %v, want capped at %v", d, policy.MaxDelay)
+	}
+}
+
+func TestMaxDuration(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		want   time.Duration
+	}{
+		{name: "with timeout", policy: DefaultPolicy(), want: time.Minute + 30*time.Second},
+		{name: "without timeout", policy: Policy{MaxRetries: 2, MaxDelay: 5 * time.Second}, want: 3*30*time.Second + 2*5*time.Second},
+		{name: "retries disabled", policy: Policy{}, want: 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.MaxDuration(30 * time.Second); got != tt.want {
+				t.Errorf("MaxDuration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,13 +5,35 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 )
 
+// DefaultProfile is the profile used when none is selected. Its settings are
+// stored at the top level of config.json, where they lived before profiles.
+const DefaultProfile = "default"
+
+// UserConfig holds the settings of a single profile
 type UserConfig struct {
-	SelectedCluster string `json:"selectedCluster,omitempty"`
+	SelectedCluster string `json:"selectedCluster,omitempty" yaml:"selectedCluster,omitempty"`
+}
+
+// Settings is the full contents of config.json
+type Settings struct {
+	UserConfig
+	CredentialStore string                 `json:"credentialStore,omitempty"` // auto, keyring or file
+	CurrentProfile  string                 `json:"currentProfile,omitempty"`
+	Profiles        map[string]*UserConfig `json:"profiles,omitempty"`
 }
 
-func getConfigDir() (string, error) {
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,63}$`)
+
+// profileOverride is the profile chosen for this process via --profile or
+// QUIC_PROFILE, taking precedence over the stored current profile
+var profileOverride string
+
+// Dir returns the directory holding the CLI configuration (~/.config/quic)
+func Dir() (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return "", fmt.Errorf("failed to get user home directory: %w", err)
@@ -22,7 +44,7 @@ func getConfigDir() (string, error) {
 }
 
 func getConfigFile() (string, error) {
-	configDir, err := getConfigDir()
+	configDir, err := Dir()
 	if err != nil {
 		return "", err
 	}
@@ -30,7 +52,40 @@ func getConfigFile() (string, error) {
 	return filepath.Join(configDir, "config.json"), nil
 }
 
-func Load() (*UserConfig, error) {
+// ValidateProfileName checks that name is usable as a profile name
+func ValidateProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use letters, digits, '-' and '_'", name)
+	}
+	return nil
+}
+
+// SetProfile selects the profile for the rest of this process
+func SetProfile(name string) error {
+	if name != "" {
+		if err := ValidateProfileName(name); err != nil {
+			return err
+		}
+	}
+	profileOverride = name
+	return nil
+}
+
+// Profile returns the active profile: the one set with SetProfile, else the
+// stored current profile, else DefaultProfile
+func Profile() string {
+	if profileOverride != "" {
+		return profileOverride
+	}
+	settings, err := LoadSettings()
+	if err != nil || settings.CurrentProfile == "" {
+		return DefaultProfile
+	}
+	return settings.CurrentProfile
+}
+
+// LoadSettings reads config.json. A missing file yields empty settings.
+func LoadSettings() (*Settings, error) {
 	configFile, err := getConfigFile()
 	if err != nil {
 		return nil, err
@@ -38,7 +93,7 @@ func Load() (*UserConfig, error) {
 
 	// If config file doesn't exist, return empty config
 	if _, err := os.Stat(configFile); os.IsNotExist(err) {
-		return &UserConfig{}, nil
+		return &Settings{}, nil
 	}
 
 	data, err := os.ReadFile(configFile)
@@ -46,16 +101,17 @@ func Load() (*UserConfig, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	var config UserConfig
-	if err := json.Unmarshal(data, &config); err != nil {
+	var settings Settings
+	if err := json.Unmarshal(data, &settings); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
-	return &config, nil
+	return &settings, nil
 }
 
-func Save(config *UserConfig) error {
-	configDir, err := getConfigDir()
+// SaveSettings writes config.json
+func SaveSettings(settings *Settings) error {
+	configDir, err := Dir()
 	if err != nil {
 		return err
 	}
@@ -70,7 +126,7 @@ func Save(config *UserConfig) error {
 		return err
 	}
 
-	data, err := json.MarshalIndent(config, "", "  ")
+	data, err := json.MarshalIndent(settings, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
@@ -82,6 +138,115 @@ func Save(config *UserConfig) error {
 	return nil
 }
 
+// ProfileConfig returns the settings of the named profile, or nil if it does
+// not exist
+func (s *Settings) ProfileConfig(name string) *UserConfig {
+	if name == DefaultProfile {
+		return &s.UserConfig
+	}
+	return s.Profiles[name]
+}
+
+// ProfileNames returns the default profile followed by the named profiles in
+// alphabetical order
+func (s *Settings) ProfileNames() []string {
+	names := make([]string, 0, len(s.Profiles)+1)
+	for name := range s.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append([]string{DefaultProfile}, names...)
+}
+
+// Load returns the settings of the active profile
+func Load() (*UserConfig, error) {
+	settings, err := LoadSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	if config := settings.ProfileConfig(Profile()); config != nil {
+		return config, nil
+	}
+	return &UserConfig{}, nil
+}
+
+// Save stores the settings of the active profile, creating the profile if
+// needed
+func Save(config *UserConfig) error {
+	settings, err := LoadSettings()
+	if err != nil {
+		return err
+	}
+
+	profile := Profile()
+	if profile == DefaultProfile {
+		settings.UserConfig = *config
+	} else {
+		if settings.Profiles == nil {
+			settings.Profiles = make(map[string]*UserConfig)
+		}
+		settings.Profiles[profile] = config
+	}
+	return SaveSettings(settings)
+}
+
+// ProfileExists reports whether the named profile has been created
+func ProfileExists(name string) (bool, error) {
+	settings, err := LoadSettings()
+	if err != nil {
+		return false, err
+	}
+	return settings.ProfileConfig(name) != nil, nil
+}
+
+// AddProfile creates the active profile if it does not exist yet
+func AddProfile() error {
+	config, err := Load()
+	if err != nil {
+		return err
+	}
+	return Save(config)
+}
+
+// UseProfile makes name the current profile for future invocations
+func UseProfile(name string) error {
+	settings, err := LoadSettings()
+	if err != nil {
+		return err
+	}
+	if settings.ProfileConfig(name) == nil {
+		return fmt.Errorf("profile %q does not exist", name)
+	}
+
+	settings.CurrentProfile = name
+	if name == DefaultProfile {
+		settings.CurrentProfile = ""
+	}
+	return SaveSettings(settings)
+}
+
+// RemoveProfile deletes a named profile's settings. The current profile falls
+// back to the default one if it was removed.
+func RemoveProfile(name string) error {
+	settings, err := LoadSettings()
+	if err != nil {
+		return err
+	}
+	if name == DefaultProfile {
+		return fmt.Errorf("the default profile cannot be removed")
+	}
+	if _, ok := settings.Profiles[name]; !ok {
+		return fmt.Errorf("profile %q does not exist", name)
+	}
+
+	delete(settings.Profiles, name)
+	if settings.CurrentProfile == name {
+		settings.CurrentProfile = ""
+	}
+	return SaveSettings(settings)
+}
+
 func SetSelectedCluster(clusterID string) error {
 	config, err := Load()
 	if err != nil {
@@ -92,6 +257,16 @@ func SetSelectedCluster(clusterID string) error {
 	return Save(config)
 }
 
+func SetCredentialStore(backend string) error {
+	settings, err := LoadSettings()
+	if err != nil {
+		return err
+	}
+
+	settings.CredentialStore = backend
+	return SaveSettings(settings)
+}
+
 func GetSelectedCluster() (string, error) {
 	config, err := Load()
 	if err != nil {
@@ -21,6 +21,7 @@ type GitHubRelease struct {
 }
 
 func GetLatestVersion() (string, error) {
+	// Not retried: this runs before every command and must stay fast
 	client := &http.Client{Timeout: 10 * time.Second}
 
 	req, err := http.NewRequest("GET", "https://api.github.com/repos/quicdb/quic-cli/releases/latest", nil)